@@ -3,19 +3,33 @@
 helpFunction()
 {
    echo -e "\tCalcc - calc languange compiler"
-   echo "Usage: $0 -d . -o out -ll"
+   echo "Usage: $0 -d . -o out -ll -B -overflow=trap"
    echo -e "\t-d\tThe dir contains main module"
    echo -e "\t-o\tThe output executable name"
    echo -e "\t-ll\tEmit .ll file"
+   echo -e "\t-B\tDisable bounds checking"
+   echo -e "\t-overflow=trap\tPanic on signed integer overflow"
    exit 1 # Exit script after printing help
 }
 
-while getopts "d:o:ll:" opt
+# -overflow=mode is passed to calccf as is, getopts only knows short options
+args=()
+for arg in "$@"
+do
+    case "$arg" in
+      -overflow=* ) calcflags="$calcflags $arg" ;;
+      * ) args+=("$arg") ;;
+    esac
+done
+set -- "${args[@]}"
+
+while getopts "d:o:ll:B" opt
 do
     case "$opt" in
       d ) ccdir="$OPTARG" ;;
       o ) outpath="$OPTARG" ;;
       ll ) llpath="$OPTARG" ;;
+      B ) calcflags="$calcflags -B" ;;
       ? ) helpFunction ;; # Print helpFunction in case parameter is non-existent
     esac
 done
@@ -53,7 +67,7 @@ fi
 # echo "$outpath"
 # echo "$llpath"
 
-calccf -d $ccdir -o $llpath
+calccf -d $ccdir -o $llpath $calcflags
 clang $llpath /usr/local/lib/uvutil.a /usr/local/lib/libuv.a /usr/local/lib/libgc.so  -ldl -static-libgcc -static-libstdc++ -lpthread  -o $outpath
 if [ "$rmll" = "true" ]
 then
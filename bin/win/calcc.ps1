@@ -3,7 +3,9 @@ param (
     [string]$o = ".\bin",
     [string]$n = "out.exe",
     [switch]$h,
-    [switch]$ll
+    [switch]$ll,
+    [switch]$B,
+    [string]$overflow = "wrap"
 )
 if ($h) {
     "   CALCC - compiler for calc language"
@@ -14,11 +16,17 @@ if ($h) {
     "       -n output executable name, default to 'out.exe'"
     "       -h print help'"
     "       -ll emit llvm'"
+    "       -B disable bounds checking'"
+    "       -overflow signed integer overflow mode, wrap or trap, default to 'wrap'"
     exit
 }
 $ErrorActionPreference = "Stop"
 mkdir "$o" -erroraction 'silentlycontinue'
-& "$env:CALC_BIN\calccf.exe" -d $d -o "$o\$n.ll"
+$flags = @("-overflow=$overflow")
+if ($B) {
+    $flags += "-B"
+}
+& "$env:CALC_BIN\calccf.exe" -d $d -o "$o\$n.ll" @flags
 if ($LastExitCode -ne 0) {
     "compile error"
     exit
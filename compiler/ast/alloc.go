@@ -15,6 +15,17 @@ func gcmalloc(m *ir.Module, s *Scope, gtp TypeNode) value.Value {
 	v := s.block.Parent.Blocks[0].NewCall(fnv)
 	return v
 }
+
+// heapAlloc is like gcmalloc, but the memory is allocated where it is
+// called instead of the entry block, so each evaluation gets new memory
+func heapAlloc(m *ir.Module, s *Scope, gtp TypeNode) value.Value {
+	gfn := s.globalScope.getGenericFunc("heapalloc")
+	if gfn == nil {
+		gfn = ScopeMap["github.com/Chronostasys/calc/runtime"].getGenericFunc("heapalloc")
+	}
+	fnv := gfn(m, gtp)
+	return s.block.NewCall(fnv)
+}
 func malloc(m *ir.Module, s *Scope, gtp TypeNode) value.Value {
 	gfn := s.globalScope.getGenericFunc("heapmalloc")
 	if gfn == nil {
@@ -0,0 +1,98 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// OverflowTrap makes + - * of signed integers panic on overflow instead
+// of wrapping around, it is turned on by the -overflow=trap flag of the
+// compiler
+var OverflowTrap = false
+
+// checkDivZeroFunc panics if the divisor is zero, checkOverflowFunc panics
+// if its param is true
+var checkDivZeroFunc, checkOverflowFunc *ir.Func
+
+// overflowFuncs maps names like sadd.i32 to the llvm *.with.overflow
+// intrinsics
+var overflowFuncs = map[string]*ir.Func{}
+
+// addArithFuncs defines the checks of integer arithmetic in m
+func addArithFuncs(m *ir.Module, printf, exit *ir.Func) {
+	i64 := lexer.DefaultIntType()
+	checkDivZeroFunc = checkFunc(m, printf, exit, "checkDivZero", "panic: runtime error: integer divide by zero",
+		func(b *ir.Block, ps []*ir.Param) value.Value {
+			return b.NewICmp(enum.IPredNE, ps[0], constant.NewInt(i64, 0))
+		}, ir.NewParam("y", i64))
+	checkOverflowFunc = checkFunc(m, printf, exit, "checkOverflow", "panic: runtime error: integer overflow",
+		func(b *ir.Block, ps []*ir.Param) value.Value {
+			return b.NewXor(ps[0], constant.True)
+		}, ir.NewParam("overflow", types.I1))
+	if !OverflowTrap {
+		return
+	}
+	for _, op := range []string{"sadd", "ssub", "smul"} {
+		for _, size := range []uint64{8, 16, 32, 64} {
+			tp := types.NewInt(size)
+			name := fmt.Sprintf("%s.i%d", op, size)
+			overflowFuncs[name] = m.NewFunc("llvm."+op+".with.overflow.i"+fmt.Sprint(size),
+				types.NewStruct(tp, types.I1), ir.NewParam("", tp), ir.NewParam("", tp))
+		}
+	}
+}
+
+// pos returns the source position of n
+func (n *BinNode) pos() string {
+	if n.Lexer == nil {
+		return "unknown position"
+	}
+	return srcPos(n.Lexer, n.SrcFile, n.Pos)
+}
+
+// checkDiv checks the integer division l / r or l % r. Division by zero
+// panics, so does MIN / -1 if the operands are signed. Constant divisors
+// are checked at compile time.
+func (n *BinNode) checkDiv(s *Scope, l, r value.Value, unsigned bool) {
+	tp := l.Type().(*types.IntType)
+	minusOne := constant.NewInt(tp, -1)
+	if c, ok := r.(*constant.Int); ok {
+		if c.X.Sign() == 0 {
+			panic(fmt.Errorf("invalid operation: division by zero (%s)", n.pos()))
+		}
+		if unsigned || c.X.Cmp(minusOne.X) != 0 {
+			return
+		}
+	} else {
+		s.block.NewCall(checkDivZeroFunc, toInt(s, r), posStr(s.m, n.pos()))
+	}
+	if unsigned {
+		return
+	}
+	min := constant.NewInt(tp, 1)
+	min.X.Lsh(min.X, uint(tp.BitSize-1)).Neg(min.X)
+	overflow := s.block.NewAnd(s.block.NewICmp(enum.IPredEQ, l, min), s.block.NewICmp(enum.IPredEQ, r, minusOne))
+	s.block.NewCall(checkOverflowFunc, overflow, posStr(s.m, n.pos()))
+}
+
+// trapOverflow calculates l op r by the intrinsic op.with.overflow, and
+// panics if it overflows. ok is false if the operation is not checked.
+func (n *BinNode) trapOverflow(s *Scope, op string, l, r value.Value) (re value.Value, ok bool) {
+	tp, isInt := l.Type().(*types.IntType)
+	if !OverflowTrap || !isInt || isUnsigned(tp) {
+		return nil, false
+	}
+	fn, ok := overflowFuncs[fmt.Sprintf("%s.i%d", op, tp.BitSize)]
+	if !ok {
+		return nil, false
+	}
+	res := s.block.NewCall(fn, l, r)
+	s.block.NewCall(checkOverflowFunc, s.block.NewExtractValue(res, 1), posStr(s.m, n.pos()))
+	return s.block.NewExtractValue(res, 0), true
+}
@@ -1,13 +1,13 @@
 package ast
 
 import (
+	"errors"
 	"fmt"
 	"log"
 	"reflect"
 	"strconv"
 	"strings"
 
-	"github.com/Chronostasys/calc/compiler/helper"
 	"github.com/Chronostasys/calc/compiler/lexer"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
@@ -26,9 +26,19 @@ var (
 		lexer.TYPE_RES_INT32:   types.I32,
 		lexer.TYPE_RES_FLOAT64: types.Double,
 		lexer.TYPE_RES_INT64:   types.I64,
-		lexer.TYPE_RES_BYTE:    types.I8,
+		lexer.TYPE_RES_BYTE:    uint8Type,
 		lexer.TYPE_RES_VOID:    types.Void,
 		lexer.TYPE_RES_STR:     getstrtp(),
+		lexer.TYPE_RES_INT8:    types.NewInt(8),
+		lexer.TYPE_RES_INT16:   types.NewInt(16),
+		lexer.TYPE_RES_UINT:    newUnsigned(lexer.DefaultIntType().BitSize),
+		lexer.TYPE_RES_UINT8:   uint8Type,
+		lexer.TYPE_RES_UINT16:  newUnsigned(16),
+		lexer.TYPE_RES_UINT32:  newUnsigned(32),
+		lexer.TYPE_RES_UINT64:  newUnsigned(64),
+		// pointers are as wide as int, the target is the host
+		lexer.TYPE_RES_UINTPTR: newUnsigned(lexer.DefaultIntType().BitSize),
+		lexer.TYPE_RES_RUNE:    types.I32,
 	}
 	initf = ir.NewFunc("init.params", types.Void)
 	initb = initf.NewBlock("")
@@ -65,6 +75,11 @@ func CheckErr() {
 	}
 }
 
+// warn reports a problem which does not fail the compilation
+func warn(format string, a ...interface{}) {
+	fmt.Printf("\033[33m[warning]\033[0m: "+format+"\n", a...)
+}
+
 type ExpNode interface {
 	Node
 	tp() TypeNode
@@ -74,6 +89,11 @@ type BinNode struct {
 	Op    int
 	Left  ExpNode
 	Right ExpNode
+	// the source position of arithmetic operations, which is reported
+	// by the runtime checks
+	Pos     int
+	Lexer   *lexer.Lexer
+	SrcFile string
 }
 
 func (b *BinNode) tp() TypeNode {
@@ -91,6 +111,7 @@ func loadIfVar(l value.Value, s *Scope) value.Value {
 		if _, ok := t.ElemType.(*types.FuncType); ok {
 			return l
 		}
+		checkNil(s, l)
 		return s.block.NewLoad(t.ElemType, l)
 	}
 	return l
@@ -121,7 +142,7 @@ func hasFloatType(b *ir.Block, ts ...value.Value) (bool, []value.Value) {
 			}
 		} else {
 			tp := v.Type().(*types.IntType)
-			if tp.BitSize > maxI.BitSize {
+			if tp.BitSize > maxI.BitSize || tp.BitSize == maxI.BitSize && isUnsigned(tp) {
 				maxI = tp
 			}
 		}
@@ -136,15 +157,20 @@ func hasFloatType(b *ir.Block, ts ...value.Value) (bool, []value.Value) {
 				} else {
 					re = append(re, b.NewFPExt(v, maxF))
 				}
+			} else if isUnsigned(v.Type()) {
+				re = append(re, b.NewUIToFP(v, maxF))
 			} else {
 				re = append(re, b.NewSIToFP(v, maxF))
 			}
 		} else {
 			t := v.Type().(*types.IntType)
-			if t.BitSize == maxI.BitSize {
-				re = append(re, v)
+			if t.BitSize != maxI.BitSize {
+				re = append(re, extInt(b, v, maxI))
+			} else if c, ok := v.(*constant.Int); ok && t != maxI {
+				// untyped constants take the type of the other operand
+				re = append(re, &constant.Int{Typ: maxI, X: c.X})
 			} else {
-				re = append(re, b.NewZExt(v, maxI))
+				re = append(re, v)
 			}
 		}
 	}
@@ -159,6 +185,15 @@ func (n *BinNode) travel(f func(Node) bool) {
 }
 
 func (n *BinNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	if n.Op != lexer.TYPE_ASSIGN {
+		c, err := constEval(n, s, -1)
+		if err == nil {
+			return constValue(m, s, c)
+		}
+		if errors.Is(err, errShiftCount) {
+			panic(fmt.Errorf("%v (%s)", err, n.pos()))
+		}
+	}
 	rawR := n.Right.calc(m, f, s)
 	r := loadIfVar(rawR, s)
 	if n.Op == lexer.TYPE_ASSIGN {
@@ -169,26 +204,43 @@ func (n *BinNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	l := loadIfVar(rawL, s)
 	hasF, re := hasFloatType(s.block, l, r)
 	l, r = re[0], re[1]
+	unsigned := isUnsigned(l.Type()) || isUnsigned(r.Type())
 	switch n.Op {
 	case lexer.TYPE_PLUS:
 		if hasF {
 			return s.block.NewFAdd(l, r)
 		}
+		if re, ok := strConcat(m, f, s, l, r); ok {
+			return re
+		}
+		if re, ok := n.trapOverflow(s, "sadd", l, r); ok {
+			return re
+		}
 		return s.block.NewAdd(l, r)
 	case lexer.TYPE_DIV:
 		if hasF {
 			return s.block.NewFDiv(l, r)
 		}
+		n.checkDiv(s, l, r, unsigned)
+		if unsigned {
+			return s.block.NewUDiv(l, r)
+		}
 		return s.block.NewSDiv(l, r)
 	case lexer.TYPE_MUL:
 		if hasF {
 			return s.block.NewFMul(l, r)
 		}
+		if re, ok := n.trapOverflow(s, "smul", l, r); ok {
+			return re
+		}
 		return s.block.NewMul(l, r)
 	case lexer.TYPE_SUB:
 		if hasF {
 			return s.block.NewFSub(l, r)
 		}
+		if re, ok := n.trapOverflow(s, "ssub", l, r); ok {
+			return re
+		}
 		return s.block.NewSub(l, r)
 	case lexer.TYPE_ASSIGN:
 		if s.assigned {
@@ -211,10 +263,17 @@ func (n *BinNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 		if hasF {
 			return s.block.NewFRem(l, r)
 		}
+		n.checkDiv(s, l, r, unsigned)
+		if unsigned {
+			return s.block.NewURem(l, r)
+		}
 		return s.block.NewSRem(l, r)
 	case lexer.TYPE_SHL:
 		return s.block.NewShl(l, r)
 	case lexer.TYPE_SHR:
+		if unsigned {
+			return s.block.NewLShr(l, r)
+		}
 		return s.block.NewAShr(l, r)
 	case lexer.TYPE_BIT_OR:
 		return s.block.NewOr(l, r)
@@ -242,6 +301,7 @@ func getVarNode(n Node) alloca {
 }
 
 func store(r, lptr value.Value, s *Scope) value.Value {
+	checkNil(s, lptr)
 	elmtp := lptr.Type().(*types.PointerType).ElemType
 	rtp := r.Type()
 	if rtp.Equal(elmtp) {
@@ -274,6 +334,10 @@ func (n *NumNode) tp() TypeNode {
 type UnaryNode struct {
 	Op    int
 	Child ExpNode
+	// the source position of -x, which is reported if it overflows
+	Pos     int
+	Lexer   *lexer.Lexer
+	SrcFile string
 }
 
 func (n *UnaryNode) tp() TypeNode {
@@ -287,6 +351,9 @@ func (n *UnaryNode) travel(f func(Node) bool) {
 var zero = constant.NewInt(types.I32, 0)
 
 func (n *UnaryNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	if c, err := constEval(n, s, -1); err == nil {
+		return c
+	}
 	c := loadIfVar(n.Child.calc(m, f, s), s)
 	switch n.Op {
 	case lexer.TYPE_PLUS:
@@ -296,6 +363,12 @@ func (n *UnaryNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 		if hasF {
 			return s.block.NewFSub(constant.NewFloat(c.Type().(*types.FloatType), 0), re[0])
 		}
+		// -x is 0 - x, so -MIN traps like other overflows
+		zero := constant.NewInt(c.Type().(*types.IntType), 0)
+		neg := &BinNode{Op: n.Op, Pos: n.Pos, Lexer: n.Lexer, SrcFile: n.SrcFile}
+		if re, ok := neg.trapOverflow(s, "ssub", zero, c); ok {
+			return re
+		}
 		return s.block.NewSub(zero, c)
 	default:
 		panic("unexpected op")
@@ -366,40 +439,59 @@ func (n *VarBlockNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 			n = n.Next
 		}
 		va = val.v
+		s.nilExp, s.nilExpPath = n, ""
 	} else {
 		va = n.parent
+		checkNil(s, va)
+		s.nilExpPath += "." + n.Token
 
-		s1 := getTypeName(va.Type())
-		s2 := helper.SplitLast(s1, ".")
-		ss := s2[0]
-		var scope = s
-		if len(s2) > 1 {
-			ss = s2[1]
-			scope = ScopeMap[s2[0]]
+		tp := structDef(va.Type(), s)
+		if tp == nil {
+			n.err()
+		}
+		// the field may be promoted from embedded fields
+		path := fieldPath(tp, n.Token, s)
+		if path == nil {
+			n.err()
 		}
-		tp := scope.getStruct(ss)
-		fi := tp.fieldsIdx[n.Token]
-		va = s.block.NewGetElementPtr(tp.structType, va,
-			constant.NewIndex(zero),
-			constant.NewIndex(constant.NewInt(types.I32, int64(fi.idx))))
+		va = gepPath(s.block, tp.structType, va, path)
 	}
 	idxs := n.Idxs
 	if len(idxs) > 0 {
 		// dereference the pointer
 		va = deReference(va, s)
 	}
-	for _, v := range idxs {
+	for i := 0; i < len(idxs); i++ {
+		if i > 0 {
+			va = deReference(va, s)
+		}
+		if se, ok := idxs[i].(*SliceExpNode); ok {
+			va = se.slice(m, f, s, va, n)
+			continue
+		}
 		innerTP := va.Type().(*types.PointerType).ElemType
 		if atp, ok := innerTP.(*types.ArrayType); ok {
 			tp := atp
-			idx := loadIfVar(v.calc(m, f, s), s)
+			checkNil(s, va)
+			idx := loadIfVar(idxs[i].calc(m, f, s), s)
+			if !n.constIndex(idx, int64(atp.Len), false) && BoundsCheck {
+				checkIndex(m, s, idx, constant.NewInt(lexer.DefaultIntType(), int64(atp.Len)), n.pos())
+			}
 			va = s.block.NewGetElementPtr(tp, va,
 				constant.NewIndex(zero),
 				idx,
 			)
-		} else {
-			va = n.getReloadIdx(va, idxs, m, f, s)
+			continue
+		}
+		// the index operators until the next slice expression
+		j := i + 1
+		for ; j < len(idxs); j++ {
+			if _, ok := idxs[j].(*SliceExpNode); ok {
+				break
+			}
 		}
+		va = n.getReloadIdx(va, idxs[i:j], m, f, s)
+		i = j - 1
 	}
 	if n.Next == nil {
 		return va
@@ -436,10 +528,16 @@ func (n *VarBlockNode) getReloadIdx(val value.Value, idxs []Node, m *ir.Module,
 		i := INDEX_RELOAD
 
 		for iter, v := range idxs {
-			ps := []Node{v}
+			ps := []Node{n.indexArg(m, f, s, deReference(val, s), v)}
 			if len(idxs)-1 == iter {
 				i = INDEX_SET_RELOAD
-				ps = append(ps, &fakeNode{v: s.rightValue})
+				rv := s.rightValue
+				if _, ok := rv.Type().(*types.PointerType); ok {
+					// pointers are taken as variables and loaded
+					rv = stackAlloc(m, s, rv.Type())
+					store(s.rightValue, rv, s)
+				}
+				ps = append(ps, &fakeNode{v: rv})
 			}
 			b := &VarBlockNode{Token: i}
 			cf := &CallFuncNode{
@@ -456,7 +554,7 @@ func (n *VarBlockNode) getReloadIdx(val value.Value, idxs []Node, m *ir.Module,
 	for _, v := range idxs {
 		cf := &CallFuncNode{
 			FnNode: b,
-			Params: []Node{v},
+			Params: []Node{n.indexArg(m, f, s, deReference(val, s), v)},
 			parent: val,
 		}
 		val = cf.calc(m, f, s)
@@ -470,10 +568,13 @@ func deReference(va value.Value, s *Scope) value.Value {
 		if ptr, ok := tpptr.(*types.PointerType); ok {
 			tpptr = ptr.ElemType
 			if _, ok := tpptr.(*types.PointerType); ok {
+				checkNil(s, va)
 				va = s.block.NewLoad(tpptr, va)
 			} else {
 				break
 			}
+		} else {
+			break
 		}
 	}
 	return va
@@ -783,6 +884,32 @@ func (n *ProgramNode) CalcGlobals(m *ir.Module) {
 		v(n.GlobalScope)
 	}
 	globalScope.funcDefFuncs = globalScope.funcDefFuncs[:0]
+	// add all constants to scope, they may refer to each other across files
+	consts := []Node{}
+	for _, v := range n.Children {
+		if _, ok := v.(*ConstNode); ok {
+			consts = append(consts, v)
+		}
+	}
+	for len(consts) > 0 {
+		failed := []Node{}
+		var err interface{}
+		for _, v := range consts {
+			func() {
+				defer func() {
+					if e := recover(); e != nil {
+						err = e
+						failed = append(failed, v)
+					}
+				}()
+				v.calc(m, nil, globalScope)
+			}()
+		}
+		if len(failed) == len(consts) {
+			panic(err)
+		}
+		consts = failed
+	}
 	// add all global variables to scope
 	for _, v := range n.Children {
 		switch v.(type) {
@@ -797,7 +924,7 @@ func (n *ProgramNode) Emit(m *ir.Module) {
 	n.CalcGlobals(m)
 	for _, v := range n.Children {
 		switch v.(type) {
-		case *DefineNode, *DefAndAssignNode:
+		case *DefineNode, *DefAndAssignNode, *ConstNode:
 		default:
 			v.calc(m, nil, globalScope)
 		}
@@ -809,8 +936,15 @@ func (n *ProgramNode) Emit(m *ir.Module) {
 	main := mi.v.(*ir.Func)
 	initb.NewRet(nil)
 	m.Funcs = append(m.Funcs, initf)
-	realmain := m.NewFunc("main", types.I32)
+	argc := ir.NewParam("argc", types.I32)
+	argv := ir.NewParam("argv", types.NewPointer(types.I8Ptr))
+	realmain := m.NewFunc("main", types.I32, argc, argv)
 	entry := realmain.NewBlock("")
+	// save command line arguments for os.Args
+	gargc, _ := ScopeMap[RUNTIME].searchVar("argc")
+	entry.NewStore(argc, gargc.v)
+	gargv, _ := ScopeMap[RUNTIME].searchVar("argv")
+	entry.NewStore(argv, gargv.v)
 	// initgc
 	setexe, _ := ScopeMap[RUNTIME].searchVar("GC_set_pages_executable")
 	entry.NewCall(setexe.v, constant.NewInt(types.I32, 1))
@@ -837,6 +971,19 @@ func (n *ProgramNode) Emit(m *ir.Module) {
 			panic(err)
 		}
 		entry.NewCall(fe.v, in) // queue main func
+		entry.NewRet(zero)
+		return
+	}
+	// the return value of an integer main is the exit code
+	if it, ok := ret.Type().(*types.IntType); ok && it.BitSize > 1 {
+		if it.BitSize > 32 {
+			entry.NewRet(entry.NewTrunc(ret, types.I32))
+		} else if it.BitSize < 32 {
+			entry.NewRet(entry.NewSExt(ret, types.I32))
+		} else {
+			entry.NewRet(ret)
+		}
+		return
 	}
 	entry.NewRet(zero)
 
@@ -895,6 +1042,9 @@ func (n *DefineNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 			s.addVar(n.ID, &variable{v: n.Val})
 		} else {
 			n.Val = stackAlloc(m, s, tp)
+			// variables start as zero values, pointers are nil so that
+			// nil checks can catch them
+			s.block.NewStore(constant.NewZeroInitializer(tp), n.Val)
 			s.addVar(n.ID, &variable{v: n.Val})
 		}
 	}
@@ -1056,10 +1206,11 @@ func (n *DefAndAssignNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value
 	var v value.Value
 	var tp types.Type
 	var tpNode TypeNode
-	if _, ok := n.ValNode.(*CallFuncNode); ok {
+	switch n.ValNode.(type) {
+	case *CallFuncNode, *ConvertNode:
 		tp = val.Type()
 		tpNode = &calcedTypeNode{tp}
-	} else {
+	default:
 		switch val.Type().(type) {
 		case *types.FloatType:
 			tp = lexer.DefaultFloatType()
@@ -1068,6 +1219,10 @@ func (n *DefAndAssignNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value
 			if val.Type().(*types.IntType).BitSize == 1 {
 				tp = val.Type()
 				tpNode = &BasicTypeNode{ResType: lexer.TYPE_RES_BOOL}
+			} else if val.Type().(*types.IntType).TypeName != "" || isUnsigned(val.Type()) {
+				// enums and unsigned ints keep their type
+				tp = val.Type()
+				tpNode = &calcedTypeNode{tp}
 			} else {
 				tp = lexer.DefaultIntType()
 				tpNode = &BasicTypeNode{ResType: lexer.TYPE_RES_INT}
@@ -1082,7 +1237,17 @@ func (n *DefAndAssignNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value
 			tpNode,
 			tp, s)
 	} else {
-		v = m.NewGlobalDef(s.getFullName(n.ID), constant.NewZeroInitializer(tp))
+		g := m.NewGlobalDef(s.getFullName(n.ID), constant.NewZeroInitializer(tp))
+		v = g
+		// constant values are set as the initializer, no need to compute
+		// them in init.params
+		if c, ok := val.(constant.Constant); ok {
+			if c, err := constCast(c, tp); err == nil {
+				g.Init = c
+				s.addVar(n.ID, &variable{v: v})
+				return v
+			}
+		}
 	}
 
 	val1, err := implicitCast(val, tp, s)
@@ -1098,6 +1263,9 @@ func (n *DefAndAssignNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value
 var nilval = constant.NewNull(types.I8Ptr)
 
 func implicitCast(v value.Value, target types.Type, s *Scope) (value.Value, error) {
+	if isFmtArg(target) && !v.Type().Equal(target) {
+		return fmtArg(v, s), nil
+	}
 	if v == nilval {
 		return constant.NewNull(target.(*types.PointerType)), nil
 	}
@@ -1107,18 +1275,28 @@ func implicitCast(v value.Value, target types.Type, s *Scope) (value.Value, erro
 	switch val := v.Type().(type) {
 	case *types.FloatType:
 		tp := v.Type().(*types.FloatType)
-		targetTp := target.(*types.FloatType)
+		targetTp, ok := target.(*types.FloatType)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %v as %v, use an explicit conversion", tp, target)
+		}
 		if targetTp.Kind < tp.Kind {
-			return nil, fmt.Errorf("failed to perform implicit cast from %T to %v", v, target)
+			return nil, fmt.Errorf("cannot implicitly narrow %v to %v, use an explicit conversion", tp, target)
 		}
 		return s.block.NewFPExt(v, targetTp), nil
 	case *types.IntType:
 		tp := v.Type().(*types.IntType)
-		targetTp := target.(*types.IntType)
+		targetTp, ok := target.(*types.IntType)
+		if !ok {
+			return nil, fmt.Errorf("cannot use %v as %v, use an explicit conversion", tp, target)
+		}
+		if c, ok := v.(*constant.Int); ok && tp.BitSize > 1 && fits(c.X, targetTp) {
+			// constants can be assigned to any int type that can hold them
+			return &constant.Int{Typ: targetTp, X: c.X}, nil
+		}
 		if targetTp.BitSize < tp.BitSize {
-			return nil, fmt.Errorf("failed to perform implicit cast from %T to %v", v, target)
+			return nil, fmt.Errorf("cannot implicitly narrow %v to %v, use an explicit conversion", tp, target)
 		}
-		return s.block.NewZExt(v, targetTp), nil
+		return extInt(s.block, v, targetTp), nil
 	case *types.PointerType:
 		v = deReference(v, s)
 		if tp, ok := target.(*types.PointerType); ok { // handle function type cast
@@ -1181,7 +1359,11 @@ func implicitCast(v value.Value, target types.Type, s *Scope) (value.Value, erro
 				fnv, err := scope.searchVar(src + "." + k)
 				// s.genericMap = old
 				if err != nil {
-					goto FAIL
+					pm := promotedMethod(v.Type(), k, s)
+					if pm == nil {
+						goto FAIL
+					}
+					fnv = &variable{v: pm}
 				}
 				fn := fnv.v.(*ir.Func)
 				for i, u := range v1.Params.Params {
@@ -1215,51 +1397,9 @@ func implicitCast(v value.Value, target types.Type, s *Scope) (value.Value, erro
 	case *interf:
 		tp, ok := target.(*interf)
 		if ok {
-			st := stackAlloc(s.m, s, tp)
-			val2 := stackAlloc(s.m, s, val)
-			store(v, val2, s)
-			for k, v1 := range tp.interfaceFuncs {
-				f := s.block.NewGetElementPtr(tp.Type, st, zero, constant.NewInt(types.I32, int64(v1.i)))
-				v2, ok := val.interfaceFuncs[k]
-				if !ok {
-					goto FAIL1
-				}
-				for i, p := range v2.Params.Params {
-					tp1, err := p.TP.calc(s)
-					if err != nil {
-						return nil, err
-					}
-					tp2, err := v1.Params.Params[i].TP.calc(s)
-					if err != nil {
-						return nil, err
-					}
-					if !tp1.Equal(tp2) {
-						goto FAIL1
-					}
-				}
-				rtp1, err := v1.RetType.calc(s)
-				if err != nil {
-					goto FAIL1
-				}
-				rtp2, err := v2.RetType.calc(s)
-				if err != nil {
-					goto FAIL1
-				}
-				if !rtp1.Equal(rtp2) {
-					goto FAIL1
-				}
-				f2 := s.block.NewGetElementPtr(val.Type, val2, zero, constant.NewInt(types.I32, int64(v2.i)))
-				loadf := loadIfVar(f2, s)
-				store(loadf, f, s)
-			}
-			// cast
-			inst := s.block.NewGetElementPtr(val.Type, val2, zero, zero)
-			ptr := s.block.NewGetElementPtr(tp.Type, st, zero, zero)
-			store(loadIfVar(inst, s), ptr, s)
-			return loadIfVar(st, s), nil
+			return castInterface(v, tp, s)
 		}
-	FAIL1:
-		return nil, fmt.Errorf("failed to cast %v to interface %v", v, target.Name())
+		return nil, fmt.Errorf("failed to cast %v to %v", v, target.Name())
 	case *types.ArrayType:
 		v1 := gcmalloc(s.m, s, &calcedTypeNode{val})
 		store(v, v1, s)
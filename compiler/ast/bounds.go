@@ -0,0 +1,157 @@
+package ast
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// BoundsCheck controls whether index and slice expressions are checked
+// at runtime, it is turned off by the -B flag of the compiler
+var BoundsCheck = true
+
+// checkIndexFunc and checkSliceFunc check the bounds and panic with the
+// source position if they are out of range. The checks are calls, so that
+// they can be inserted into any expression without splitting its block.
+var checkIndexFunc, checkSliceFunc *ir.Func
+
+// posStrs caches the source positions passed to the checks in each module,
+// the modules used for type inference must not share the real one's globals
+var posStrs = map[*ir.Module]map[string]constant.Constant{}
+
+// checkFunc defines a function checking its params with ok, it prints the
+// message made from format and the params if the check fails, and exits.
+// Only the params matching the verbs of format are printed, the source
+// position is added as the last param.
+func checkFunc(m *ir.Module, printf, exit *ir.Func, name, format string, ok func(b *ir.Block, ps []*ir.Param) value.Value, params ...*ir.Param) *ir.Func {
+	g := m.NewGlobalDef(name+"..fmt", constant.NewCharArrayFromString(format+" (%s)\n\x00"))
+	g.Immutable = true
+	f := m.NewFunc(name, types.Void, append(params, ir.NewParam("pos", types.I8Ptr))...)
+	b := f.NewBlock("")
+	fail := f.NewBlock("")
+	ret := f.NewBlock("")
+	b.NewCondBr(ok(b, f.Params), ret, fail)
+	ret.NewRet(nil)
+	args := []value.Value{constant.NewGetElementPtr(g.Typ.ElemType, g, zero, zero)}
+	verbs := strings.Count(format, "%") - 2*strings.Count(format, "%%")
+	for _, p := range f.Params[:verbs] {
+		args = append(args, p)
+	}
+	args = append(args, f.Params[len(f.Params)-1])
+	fail.NewCall(printf, args...)
+	fail.NewCall(exit, constant.NewInt(types.I32, 2))
+	fail.NewUnreachable()
+	return f
+}
+
+// addBoundsFuncs defines checkIndexFunc and checkSliceFunc in m
+func addBoundsFuncs(m *ir.Module, printf, exit *ir.Func) {
+	i64 := lexer.DefaultIntType()
+	// negative values fail the unsigned comparisons
+	checkIndexFunc = checkFunc(m, printf, exit, "checkIndex", "panic: runtime error: index out of range [%lld] with length %lld",
+		func(b *ir.Block, ps []*ir.Param) value.Value {
+			return b.NewICmp(enum.IPredULT, ps[0], ps[1])
+		}, ir.NewParam("i", i64), ir.NewParam("len", i64))
+	checkSliceFunc = checkFunc(m, printf, exit, "checkSlice", "panic: runtime error: slice bounds out of range [%lld:%lld] with capacity %lld",
+		func(b *ir.Block, ps []*ir.Param) value.Value {
+			return b.NewAnd(b.NewICmp(enum.IPredULE, ps[0], ps[1]), b.NewICmp(enum.IPredULE, ps[1], ps[2]))
+		}, ir.NewParam("low", i64), ir.NewParam("high", i64), ir.NewParam("cap", i64))
+}
+
+// posStr returns the c string of pos
+func posStr(m *ir.Module, pos string) constant.Constant {
+	strs := posStrs[m]
+	if strs == nil {
+		strs = map[string]constant.Constant{}
+		posStrs[m] = strs
+	}
+	if c, ok := strs[pos]; ok {
+		return c
+	}
+	ch := constant.NewCharArrayFromString(pos + "\x00")
+	g := m.NewGlobalDef(fmt.Sprintf("..pos%d", len(strs)), ch)
+	g.Immutable = true
+	c := constant.NewGetElementPtr(ch.Typ, g, zero, zero)
+	strs[pos] = c
+	return c
+}
+
+// pos returns the source position of n, like main.calc:3:5
+func (n *VarBlockNode) pos() string {
+	if n.Lexer == nil {
+		return n.Token
+	}
+	return srcPos(n.Lexer, n.SrcFile, n.Pos)
+}
+
+func srcPos(l *lexer.Lexer, file string, pos int) string {
+	ln, off := l.Currpos(pos)
+	return fmt.Sprintf("%s:%d:%d", file, ln, off)
+}
+
+// toInt extends integer v to the default int type
+func toInt(s *Scope, v value.Value) value.Value {
+	if v.Type().Equal(lexer.DefaultIntType()) {
+		return v
+	}
+	return extInt(s.block, v, lexer.DefaultIntType())
+}
+
+// isSliceType reports whether tp is a slice, which is indexed by the
+// IndexOp method and has to be checked
+func isSliceType(tp types.Type) bool {
+	return strings.HasPrefix(getTypeName(tp), SLICE+".Slice<")
+}
+
+// checkIndex checks 0 <= idx < l
+func checkIndex(m *ir.Module, s *Scope, idx, l value.Value, pos string) {
+	s.block.NewCall(checkIndexFunc, toInt(s, idx), toInt(s, l), posStr(m, pos))
+}
+
+// checkSlice checks 0 <= low <= high <= c
+func checkSlice(m *ir.Module, s *Scope, low, high, c value.Value, pos string) {
+	s.block.NewCall(checkSliceFunc, toInt(s, low), toInt(s, high), toInt(s, c), posStr(m, pos))
+}
+
+// constIndex checks the constant index idx at compile time, and reports
+// whether it is constant. l is the length, or -1 if it is unknown. The
+// indices of slice expressions can be equal to the length.
+func (n *VarBlockNode) constIndex(idx value.Value, l int64, slicing bool) bool {
+	c, ok := idx.(*constant.Int)
+	if !ok {
+		return false
+	}
+	if c.X.Sign() < 0 {
+		panic(fmt.Errorf("invalid index %v (index must be non-negative) (%s)", c.X, n.pos()))
+	}
+	max := l
+	if slicing {
+		max++
+	}
+	if l >= 0 && c.X.Cmp(big.NewInt(max)) >= 0 {
+		panic(fmt.Errorf("invalid index %v (out of bounds for %d-element array) (%s)", c.X, l, n.pos()))
+	}
+	return true
+}
+
+// indexArg returns the index argument of x[idx] for the IndexOp methods,
+// the index is checked if x is a slice or a string
+func (n *VarBlockNode) indexArg(m *ir.Module, f *ir.Func, s *Scope, x value.Value, idx Node) Node {
+	if !isSliceType(x.Type()) && getTypeName(x.Type()) != STRINGS+"._str" {
+		return idx
+	}
+	iv := loadIfVar(idx.calc(m, f, s), s)
+	n.constIndex(iv, -1, false)
+	if BoundsCheck {
+		l := loadIfVar(callMethod(m, f, s, x, &CallFuncNode{FnNode: &VarBlockNode{Token: "Len"}}), s)
+		checkIndex(m, s, iv, l, n.pos())
+	}
+	return &fakeNode{v: iv}
+}
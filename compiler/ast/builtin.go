@@ -0,0 +1,108 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// builtinMethods maps the builtin functions to the methods they call,
+// len(x) is x.Len() and delete(m, k) is m.Delete(k). range(x) is
+// generated by range loops, it cannot be written by users.
+var builtinMethods = map[string]string{
+	"len":    "Len",
+	"cap":    "Cap",
+	"append": "AppendElems",
+	"copy":   "Copy",
+	"delete": "Delete",
+	"range":  "Iter",
+}
+
+// builtin calls the builtin function n if it is one. Arrays are turned
+// into slices sharing their memory, and strings are taken as []byte
+// when they are copied or appended.
+func (n *CallFuncNode) builtin(m *ir.Module, f *ir.Func, s *Scope) (value.Value, bool) {
+	varNode, ok := n.FnNode.(*VarBlockNode)
+	if !ok || n.parent != nil || varNode.Next != nil || len(varNode.Idxs) > 0 || len(n.Generics) > 0 {
+		return nil, false
+	}
+	method, ok := builtinMethods[varNode.Token]
+	if !ok {
+		return nil, false
+	}
+	if _, err := s.searchVar(varNode.Token); err == nil {
+		return nil, false
+	}
+	if len(n.Params) == 0 {
+		panic(fmt.Errorf("missing argument to %s", varNode.Token))
+	}
+	v := n.Params[0].calc(m, f, s)
+	if _, ok := v.Type().(*types.PointerType); !ok {
+		alloc := stackAlloc(m, s, v.Type())
+		store(v, alloc, s)
+		v = alloc
+	}
+	v = deReference(v, s)
+	if atp, ok := v.Type().(*types.PointerType).ElemType.(*types.ArrayType); ok {
+		switch varNode.Token {
+		case "len", "cap":
+			return constant.NewInt(lexer.DefaultIntType(), int64(atp.Len)), true
+		}
+		v = arrSlice(m, s, v)
+	}
+	if structDef(v.Type(), s) == nil {
+		panic(fmt.Errorf("invalid argument %v for %s", v.Type(), varNode.Token))
+	}
+	params := n.Params[1:]
+	if len(params) > 0 && (varNode.Token == "copy" || n.Spread) {
+		last := len(params) - 1
+		params = append(params[:last:last], sliceArg(m, f, s, params[last]))
+	}
+	re := callMethod(m, f, s, v, &CallFuncNode{
+		FnNode: &VarBlockNode{Token: method},
+		Params: params,
+		Next:   n.Next,
+		Spread: n.Spread,
+	})
+	if (varNode.Token == "len" || varNode.Token == "cap") && n.Next == nil {
+		// slices use int32 as length, but len and cap always return int
+		l := loadIfVar(re, s)
+		if tp, ok := l.Type().(*types.IntType); ok && tp.BitSize < lexer.DefaultIntType().BitSize {
+			return extInt(s.block, l, lexer.DefaultIntType()), true
+		}
+	}
+	return re, true
+}
+
+// sliceArg turns the source argument of copy and append into a slice,
+// arrays share their memory and strings are copied by Bytes
+func sliceArg(m *ir.Module, f *ir.Func, s *Scope, n Node) Node {
+	v := n.calc(m, f, s)
+	tp := v.Type()
+	if p, ok := tp.(*types.PointerType); ok {
+		tp = p.ElemType
+	}
+	switch tp := tp.(type) {
+	case *types.ArrayType:
+		re := arrSlice(m, s, v)
+		alloc := stackAlloc(m, s, re.Type())
+		store(re, alloc, s)
+		v = alloc
+	case *types.StructType:
+		if isStrType(tp) {
+			if _, ok := v.Type().(*types.PointerType); !ok {
+				alloc := stackAlloc(m, s, v.Type())
+				store(v, alloc, s)
+				v = alloc
+			}
+			v = callMethod(m, f, s, v, &CallFuncNode{
+				FnNode: &VarBlockNode{Token: "Bytes"},
+			})
+		}
+	}
+	return &fakeNode{v: v}
+}
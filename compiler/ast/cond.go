@@ -48,19 +48,26 @@ func (n *CompareNode) travel(f func(Node) bool) {
 type e struct {
 	IntE   enum.IPred
 	FloatE enum.FPred
+	UIntE  enum.IPred
 }
 
 var comparedic = map[int]e{
-	lexer.TYPE_EQ:  {enum.IPredEQ, enum.FPredOEQ},
-	lexer.TYPE_NEQ: {enum.IPredNE, enum.FPredONE},
-	lexer.TYPE_LG:  {enum.IPredSGT, enum.FPredOGT},
-	lexer.TYPE_LEQ: {enum.IPredSGE, enum.FPredOGE},
-	lexer.TYPE_SM:  {enum.IPredSLT, enum.FPredOLT},
-	lexer.TYPE_SEQ: {enum.IPredSLE, enum.FPredOLE},
+	lexer.TYPE_EQ:  {enum.IPredEQ, enum.FPredOEQ, enum.IPredEQ},
+	lexer.TYPE_NEQ: {enum.IPredNE, enum.FPredONE, enum.IPredNE},
+	lexer.TYPE_LG:  {enum.IPredSGT, enum.FPredOGT, enum.IPredUGT},
+	lexer.TYPE_LEQ: {enum.IPredSGE, enum.FPredOGE, enum.IPredUGE},
+	lexer.TYPE_SM:  {enum.IPredSLT, enum.FPredOLT, enum.IPredULT},
+	lexer.TYPE_SEQ: {enum.IPredSLE, enum.FPredOLE, enum.IPredULE},
 }
 
 func (n *CompareNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	if c, err := constEval(n, s, -1); err == nil {
+		return c
+	}
 	l, r := loadIfVar(n.Left.calc(m, f, s), s), loadIfVar(n.Right.calc(m, f, s), s)
+	if re, ok := strCompare(m, f, s, n.Op, l, r); ok {
+		return re
+	}
 	hasF, re := hasFloatType(s.block, l, r)
 	l, r = re[0], re[1]
 	_, ok1 := r.Type().(*types.PointerType)
@@ -81,12 +88,14 @@ func (n *CompareNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 				panic("expect nil")
 			}
 		}
-		return s.block.NewICmp(comparedic[n.Op].IntE,
+		return s.block.NewICmp(comparedic[n.Op].UIntE,
 			l,
 			r,
 		)
 	} else if hasF {
 		return s.block.NewFCmp(comparedic[n.Op].FloatE, l, r)
+	} else if isUnsigned(l.Type()) || isUnsigned(r.Type()) {
+		return s.block.NewICmp(comparedic[n.Op].UIntE, l, r)
 	} else {
 		return s.block.NewICmp(comparedic[n.Op].IntE, l, r)
 	}
@@ -109,13 +118,14 @@ func (n *IfNode) travel(f func(Node) bool) {
 func (n *IfNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	blockID++
 	tt := f.NewBlock(strconv.Itoa(blockID))
-	n.Statements.calc(m, f, s.addChildScope(tt))
 	blockID++
 	end := f.NewBlock(strconv.Itoa(blockID))
 	s.block.NewCondBr(loadIfVar(n.BoolExp.calc(m, f, s), s), tt, end)
 	s.block = end
-	if tt.Term == nil {
-		tt.NewBr(end)
+	child := s.addChildScope(tt)
+	n.Statements.calc(m, f, child)
+	if child.block.Term == nil {
+		child.block.NewBr(end)
 	}
 	if s.parent.block != nil {
 		end.NewBr(s.parent.block)
@@ -9,5 +9,8 @@ const (
 	CORO_SYNC_MOD    = "github.com/Chronostasys/calc/runtime/coro/sync"
 	LIBUV            = "github.com/Chronostasys/calc/runtime/libuv"
 	SLICE            = "github.com/Chronostasys/calc/runtime/slice"
+	MAPS             = "github.com/Chronostasys/calc/runtime/maps"
+	STRINGS          = "github.com/Chronostasys/calc/runtime/strings"
+	FMT_MOD          = "github.com/Chronostasys/calc/runtime/fmt"
 	RUNTIME          = "github.com/Chronostasys/calc/runtime"
 )
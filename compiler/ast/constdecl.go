@@ -0,0 +1,526 @@
+package ast
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// ConstNode const declaration, either a single spec or a const block
+type ConstNode struct {
+	Specs []*ConstSpec
+}
+
+// ConstSpec one constant in a const declaration. Inside a const block a spec
+// without value repeats the type and value of the previous one, while Iota
+// keeps counting.
+type ConstSpec struct {
+	ID   string
+	TP   TypeNode
+	Val  ExpNode
+	Iota int
+}
+
+func (n *ConstNode) travel(f func(Node) bool) {
+	f(n)
+}
+
+func (n *ConstNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	for _, v := range n.Specs {
+		c, err := constEval(v.Val, s, v.Iota)
+		if err != nil {
+			panic(fmt.Errorf("const %s: %v", v.ID, err))
+		}
+		if v.TP != nil {
+			tp, err := v.TP.calc(s)
+			if err != nil {
+				panic(err)
+			}
+			c, err = constCast(c, tp)
+			if err != nil {
+				panic(fmt.Errorf("const %s: %v", v.ID, err))
+			}
+		}
+		s.addVar(v.ID, &variable{v: constValue(m, s, c)})
+	}
+	return zero
+}
+
+// constValue turns the result of constEval into a value usable in ir,
+// strings are emitted as globals
+func constValue(m *ir.Module, s *Scope, c constant.Constant) constant.Constant {
+	if str, ok := c.(*constant.CharArray); ok {
+		return constStr(m, string(str.X))
+	}
+	return c
+}
+
+var errNotConst = fmt.Errorf("not a constant expression")
+
+// errShiftCount is wrapped by the errors of invalid constant shift counts,
+// which are reported even if the expression is not a constant declaration
+var errShiftCount = fmt.Errorf("invalid shift count")
+
+// constEval evaluates n at compile time. iota is the value of the `iota`
+// identifier inside const declarations, -1 elsewhere.
+// Strings are evaluated to *constant.CharArray.
+func constEval(n Node, s *Scope, iota int) (constant.Constant, error) {
+	switch node := n.(type) {
+	case *NumNode:
+		if c, ok := node.Val.(constant.Constant); ok {
+			return c, nil
+		}
+	case *BoolConstNode:
+		return constant.NewBool(node.Val), nil
+	case *StringNode:
+		return constant.NewCharArrayFromString(node.Str), nil
+	case *TakeValNode:
+		if node.Level == 0 {
+			return constEval(node.Node, s, iota)
+		}
+	case *VarBlockNode:
+		if len(node.Idxs) > 0 {
+			return nil, errNotConst
+		}
+		val, err := s.searchVar(node.Token)
+		if err != nil && node.Token == "iota" && node.Next == nil && iota >= 0 {
+			return untypedInt(big.NewInt(int64(iota)), 8), nil
+		}
+		if err != nil {
+			scope := ScopeMap[node.Token]
+			if scope == nil || node.Next == nil {
+				return nil, err
+			}
+			node = node.Next
+			val, err = scope.searchVar(node.Token)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if node.Next != nil {
+			return nil, errNotConst
+		}
+		switch c := val.v.(type) {
+		case *constant.Int, *constant.Float:
+			return c.(constant.Constant), nil
+		case *constant.Struct:
+			if str, ok := constStrVal(c); ok {
+				return constant.NewCharArrayFromString(str), nil
+			}
+		}
+	case *ConvertNode:
+		c, err := constEval(node.Val, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		tp, err := node.TP.calc(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := c.(*constant.CharArray); ok && tp.Equal(getstrtp()) {
+			return c, nil
+		}
+		return constCast(c, tp)
+	case *CallFuncNode:
+		if conv := node.convNode(s); conv != nil {
+			return constEval(conv, s, iota)
+		}
+		if fn, ok := node.FnNode.(*VarBlockNode); ok && fn.Token == "sizeof" &&
+			fn.Next == nil && node.Next == nil && len(node.Generics) == 1 {
+			tp, err := node.Generics[0].calc(s)
+			if err != nil {
+				return nil, err
+			}
+			size, _, err := typeSize(tp)
+			if err != nil {
+				return nil, err
+			}
+			return constant.NewInt(lexer.DefaultIntType(), size), nil
+		}
+	case *UnaryNode:
+		c, err := constEval(node.Child, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		switch node.Op {
+		case lexer.TYPE_PLUS:
+			return c, nil
+		case lexer.TYPE_SUB:
+			return constBinOp(lexer.TYPE_SUB, untypedInt(big.NewInt(0), 8), c)
+		}
+	case *BinNode:
+		if node.Op == lexer.TYPE_ASSIGN {
+			return nil, errNotConst
+		}
+		l, err := constEval(node.Left, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		r, err := constEval(node.Right, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		return constBinOp(node.Op, l, r)
+	case *CompareNode:
+		l, err := constEval(node.Left, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		r, err := constEval(node.Right, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		return constCompare(node.Op, l, r)
+	case *BoolExpNode:
+		l, err := constEval(node.Left, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		r, err := constEval(node.Right, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		lb, lok := constBool(l)
+		rb, rok := constBool(r)
+		if !lok || !rok {
+			return nil, errNotConst
+		}
+		if node.Op == lexer.TYPE_AND {
+			return constant.NewBool(lb && rb), nil
+		}
+		return constant.NewBool(lb || rb), nil
+	case *NotNode:
+		c, err := constEval(node.Bool, s, iota)
+		if err != nil {
+			return nil, err
+		}
+		b, ok := constBool(c)
+		if !ok {
+			return nil, errNotConst
+		}
+		return constant.NewBool(!b), nil
+	}
+	return nil, errNotConst
+}
+
+func constBool(c constant.Constant) (bool, bool) {
+	if i, ok := c.(*constant.Int); ok && i.Typ.BitSize == 1 {
+		return i.X.Sign() != 0, true
+	}
+	return false, false
+}
+
+// constStrVal returns the content of a string constant created by constStr
+func constStrVal(c *constant.Struct) (string, bool) {
+	if !c.Typ.Equal(getstrtp()) || len(c.Fields) != 2 {
+		return "", false
+	}
+	gep, ok := c.Fields[0].(*constant.ExprGetElementPtr)
+	if !ok {
+		return "", false
+	}
+	g, ok := gep.Src.(*ir.Global)
+	if !ok {
+		return "", false
+	}
+	ch, ok := g.Init.(*constant.CharArray)
+	if !ok {
+		return "", false
+	}
+	// the NUL after the bytes is not part of the string
+	l, ok := c.Fields[1].(*constant.Int)
+	if !ok {
+		return "", false
+	}
+	return string(ch.X[:l.X.Int64()]), true
+}
+
+// untypedInt returns the narrowest int constant (at least min bits) that
+// holds x, which is how the parser types integer literals as well.
+func untypedInt(x *big.Int, min uint64) *constant.Int {
+	bw := min
+	for bw < 64 {
+		lim := new(big.Int).Lsh(big.NewInt(1), uint(bw-1))
+		if x.Cmp(lim) < 0 && x.Cmp(new(big.Int).Neg(lim)) >= 0 {
+			break
+		}
+		bw *= 2
+	}
+	return &constant.Int{Typ: types.NewInt(bw), X: x}
+}
+
+// fits reports whether x can be represented by tp. Signed types hold
+// -2^(n-1) .. 2^(n-1)-1 and unsigned types hold 0 .. 2^n-1, so 0xff, which
+// is parsed as i16, fits in byte but not in int8
+func fits(x *big.Int, tp *types.IntType) bool {
+	if tp.BitSize == 1 {
+		return x.Sign() == 0 || x.Cmp(big.NewInt(1)) == 0
+	}
+	if isUnsigned(tp) {
+		max := new(big.Int).Lsh(big.NewInt(1), uint(tp.BitSize))
+		return x.Sign() >= 0 && x.Cmp(max) < 0
+	}
+	max := new(big.Int).Lsh(big.NewInt(1), uint(tp.BitSize-1))
+	min := new(big.Int).Neg(max)
+	return x.Cmp(max) < 0 && x.Cmp(min) >= 0
+}
+
+func constBinOp(op int, l, r constant.Constant) (constant.Constant, error) {
+	ls, lok := l.(*constant.CharArray)
+	rs, rok := r.(*constant.CharArray)
+	if lok || rok {
+		if !lok || !rok || op != lexer.TYPE_PLUS {
+			return nil, errNotConst
+		}
+		return constant.NewCharArray(append(append([]byte{}, ls.X...), rs.X...)), nil
+	}
+	li, lok := l.(*constant.Int)
+	ri, rok := r.(*constant.Int)
+	if lok && rok {
+		x := new(big.Int)
+		switch op {
+		case lexer.TYPE_PLUS:
+			x.Add(li.X, ri.X)
+		case lexer.TYPE_SUB:
+			x.Sub(li.X, ri.X)
+		case lexer.TYPE_MUL:
+			x.Mul(li.X, ri.X)
+		case lexer.TYPE_DIV, lexer.TYPE_PS:
+			if ri.X.Sign() == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if op == lexer.TYPE_DIV {
+				x.Quo(li.X, ri.X)
+			} else {
+				x.Rem(li.X, ri.X)
+			}
+		case lexer.TYPE_SHL, lexer.TYPE_SHR:
+			if err := checkShift(li, ri); err != nil {
+				return nil, err
+			}
+			if op == lexer.TYPE_SHL {
+				x.Lsh(li.X, uint(ri.X.Uint64()))
+			} else {
+				x.Rsh(li.X, uint(ri.X.Uint64()))
+			}
+		case lexer.TYPE_BIT_OR:
+			x.Or(li.X, ri.X)
+		case lexer.TYPE_BIT_XOR:
+			x.Xor(li.X, ri.X)
+		case lexer.TYPE_ESP:
+			x.And(li.X, ri.X)
+		default:
+			return nil, errNotConst
+		}
+		tp := li.Typ
+		if ri.Typ.BitSize > tp.BitSize || typedConst(ri.Typ) {
+			tp = ri.Typ
+		}
+		if typedConst(li.Typ) {
+			tp = li.Typ
+		}
+		if typedConst(tp) {
+			// typed constants keep their type
+			if !fits(x, tp) {
+				return nil, fmt.Errorf("constant %s overflows %s", x, tp)
+			}
+			return &constant.Int{Typ: tp, X: x}, nil
+		}
+		c := untypedInt(x, tp.BitSize)
+		if !fits(x, c.Typ) {
+			return nil, fmt.Errorf("constant %s overflows int64", x)
+		}
+		return c, nil
+	}
+	lf, err := constToFloat(l)
+	if err != nil {
+		return nil, err
+	}
+	rf, err := constToFloat(r)
+	if err != nil {
+		return nil, err
+	}
+	x := new(big.Float)
+	switch op {
+	case lexer.TYPE_PLUS:
+		x.Add(lf.X, rf.X)
+	case lexer.TYPE_SUB:
+		x.Sub(lf.X, rf.X)
+	case lexer.TYPE_MUL:
+		x.Mul(lf.X, rf.X)
+	case lexer.TYPE_DIV:
+		if rf.X.Sign() == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		x.Quo(lf.X, rf.X)
+	default:
+		return nil, errNotConst
+	}
+	tp := lf.Typ
+	if rf.Typ.Kind > tp.Kind {
+		tp = rf.Typ
+	}
+	return &constant.Float{Typ: tp, X: x}, nil
+}
+
+// checkShift checks the count of a constant shift, it must not be negative
+// and must be less than the bit size of the operand. Untyped constants
+// become int, so they can be shifted by less than its bit size
+func checkShift(l, count *constant.Int) error {
+	if count.X.Sign() < 0 {
+		return fmt.Errorf("%w %s (must be non-negative)", errShiftCount, count.X)
+	}
+	bw := lexer.DefaultIntType().BitSize
+	if typedConst(l.Typ) {
+		bw = l.Typ.BitSize
+	}
+	if count.X.Cmp(big.NewInt(int64(bw))) >= 0 {
+		return fmt.Errorf("%w %s (must be less than %d)", errShiftCount, count.X, bw)
+	}
+	return nil
+}
+
+// typedConst reports whether a constant of tp was declared with a type,
+// literals get the narrowest signed type instead
+func typedConst(tp *types.IntType) bool {
+	return tp.TypeName != "" || isUnsigned(tp)
+}
+
+// constCompare compares two constants, the result is an i1 constant
+func constCompare(op int, l, r constant.Constant) (constant.Constant, error) {
+	var cmp int
+	ls, lok := l.(*constant.CharArray)
+	rs, rok := r.(*constant.CharArray)
+	li, liok := l.(*constant.Int)
+	ri, riok := r.(*constant.Int)
+	switch {
+	case lok && rok:
+		cmp = strings.Compare(string(ls.X), string(rs.X))
+	case lok || rok:
+		return nil, errNotConst
+	case liok && riok:
+		cmp = li.X.Cmp(ri.X)
+	default:
+		lf, err := constToFloat(l)
+		if err != nil {
+			return nil, err
+		}
+		rf, err := constToFloat(r)
+		if err != nil {
+			return nil, err
+		}
+		cmp = lf.X.Cmp(rf.X)
+	}
+	switch op {
+	case lexer.TYPE_EQ:
+		return constant.NewBool(cmp == 0), nil
+	case lexer.TYPE_NEQ:
+		return constant.NewBool(cmp != 0), nil
+	case lexer.TYPE_LG:
+		return constant.NewBool(cmp > 0), nil
+	case lexer.TYPE_LEQ:
+		return constant.NewBool(cmp >= 0), nil
+	case lexer.TYPE_SM:
+		return constant.NewBool(cmp < 0), nil
+	case lexer.TYPE_SEQ:
+		return constant.NewBool(cmp <= 0), nil
+	}
+	return nil, errNotConst
+}
+
+func constToFloat(c constant.Constant) (*constant.Float, error) {
+	switch c := c.(type) {
+	case *constant.Float:
+		return c, nil
+	case *constant.Int:
+		return &constant.Float{Typ: types.Float, X: new(big.Float).SetInt(c.X)}, nil
+	}
+	return nil, errNotConst
+}
+
+// constCast converts a constant to the declared type of a typed constant
+func constCast(c constant.Constant, tp types.Type) (constant.Constant, error) {
+	switch tp := tp.(type) {
+	case *types.IntType:
+		if i, ok := c.(*constant.Int); ok {
+			if !fits(i.X, tp) {
+				return nil, fmt.Errorf("constant %s overflows %s", i.X, tp)
+			}
+			return &constant.Int{Typ: tp, X: new(big.Int).Set(i.X)}, nil
+		}
+	case *types.StructType:
+		if _, ok := c.(*constant.CharArray); ok && tp.Equal(getstrtp()) {
+			return c, nil
+		}
+	case *types.FloatType:
+		f, err := constToFloat(c)
+		if err != nil {
+			return nil, err
+		}
+		return &constant.Float{Typ: tp, X: new(big.Float).Set(f.X)}, nil
+	}
+	if c.Type().Equal(tp) {
+		return c, nil
+	}
+	return nil, fmt.Errorf("cannot use %v as %v", c, tp)
+}
+
+// typeSize returns the size and alignment of tp in bytes, which is what
+// sizeof<T>() computes at runtime. Like int, the target is assumed to be
+// the host, pointers are as wide as int and scalars are aligned to their
+// size, which holds on x86_64 and arm64 but not on 32 bit x86
+func typeSize(tp types.Type) (size, align int64, err error) {
+	switch tp := tp.(type) {
+	case *interf:
+		return typeSize(tp.Type)
+	case *types.IntType:
+		size = 1
+		for size*8 < int64(tp.BitSize) {
+			size *= 2
+		}
+		return size, size, nil
+	case *types.FloatType:
+		switch tp.Kind {
+		case types.FloatKindHalf:
+			return 2, 2, nil
+		case types.FloatKindFloat:
+			return 4, 4, nil
+		case types.FloatKindDouble:
+			return 8, 8, nil
+		}
+		return 16, 16, nil
+	case *types.PointerType:
+		size = int64(lexer.DefaultIntType().BitSize / 8)
+		return size, size, nil
+	case *types.ArrayType:
+		size, align, err = typeSize(tp.ElemType)
+		return size * int64(tp.Len), align, err
+	case *types.StructType:
+		if tp.Opaque {
+			break
+		}
+		align = 1
+		for _, f := range tp.Fields {
+			fs, fa, err := typeSize(f)
+			if err != nil {
+				return 0, 0, err
+			}
+			if tp.Packed {
+				fa = 1
+			}
+			size = (size + fa - 1) / fa * fa
+			size += fs
+			if fa > align {
+				align = fa
+			}
+		}
+		return (size + align - 1) / align * align, align, nil
+	}
+	return 0, 0, fmt.Errorf("cannot get size of %v", tp)
+}
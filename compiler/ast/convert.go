@@ -0,0 +1,174 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// ConvertNode explicit type conversion T(x)
+type ConvertNode struct {
+	TP  TypeNode
+	Val ExpNode
+}
+
+func (n *ConvertNode) tp() TypeNode {
+	return n.TP
+}
+
+func (n *ConvertNode) travel(f func(Node) bool) {
+	f(n)
+	n.Val.travel(f)
+}
+
+func (n *ConvertNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	tp, err := n.TP.calc(s)
+	if err != nil {
+		panic(err)
+	}
+	v := loadIfVar(n.Val.calc(m, f, s), s)
+	re, err := convert(v, tp, s)
+	if err != nil {
+		panic(err)
+	}
+	if _, ok := re.Type().(*types.PointerType); ok {
+		// pointer values are treated as variables, keep them in an alloc
+		// like the results of function calls
+		alloc := stackAlloc(m, s, re.Type())
+		store(re, alloc, s)
+		return alloc
+	}
+	return re
+}
+
+// convert converts v to target. Besides all implicit casts, it converts
+// between numeric types, types with the same underlying type, and
+// string and []byte.
+func convert(v value.Value, target types.Type, s *Scope) (value.Value, error) {
+	if c, ok := v.(constant.Constant); ok {
+		if c1, err := constCast(c, target); err == nil {
+			return c1, nil
+		}
+	}
+	strtp := getstrtp()
+	if v.Type().Equal(strtp) && isByteSlice(target, s) {
+		bs, _ := ScopeMap[STRINGS].searchVar("_str.Bytes")
+		return s.block.NewCall(bs.v, v), nil
+	}
+	if target.Equal(strtp) && isByteSlice(v.Type(), s) {
+		fb, _ := ScopeMap[STRINGS].searchVar("FromBytes")
+		p, err := implicitCast(v, fb.v.(*ir.Func).Sig.Params[0], s)
+		if err != nil {
+			return nil, err
+		}
+		return s.block.NewCall(fb.v, p), nil
+	}
+	switch src := v.Type().(type) {
+	case *types.IntType:
+		switch tp := target.(type) {
+		case *types.IntType:
+			switch {
+			case src.BitSize > tp.BitSize:
+				return s.block.NewTrunc(v, tp), nil
+			case src.BitSize < tp.BitSize:
+				return extInt(s.block, v, tp), nil
+			case src == tp:
+				return v, nil
+			}
+			// same size, only the sign or the name changes
+			return s.block.NewBitCast(v, tp), nil
+		case *types.FloatType:
+			if isUnsigned(src) {
+				return s.block.NewUIToFP(v, tp), nil
+			}
+			return s.block.NewSIToFP(v, tp), nil
+		}
+	case *types.FloatType:
+		switch tp := target.(type) {
+		case *types.IntType:
+			if isUnsigned(tp) {
+				return s.block.NewFPToUI(v, tp), nil
+			}
+			return s.block.NewFPToSI(v, tp), nil
+		case *types.FloatType:
+			switch {
+			case src.Kind > tp.Kind:
+				return s.block.NewFPTrunc(v, tp), nil
+			case src.Kind < tp.Kind:
+				return s.block.NewFPExt(v, tp), nil
+			case src == tp:
+				return v, nil
+			}
+			return s.block.NewBitCast(v, tp), nil
+		}
+	case *types.PointerType:
+		// pointers and function types
+		if tp, ok := target.(*types.PointerType); ok && sameUnderlying(src.ElemType, tp.ElemType) {
+			return s.block.NewBitCast(v, tp), nil
+		}
+	case *types.StructType:
+		if tp, ok := target.(*types.StructType); ok && sameUnderlying(src, tp) {
+			if src.Equal(tp) {
+				return v, nil
+			}
+			ptr := stackAlloc(s.m, s, tp)
+			store(v, s.block.NewBitCast(ptr, types.NewPointer(src)), s)
+			return s.block.NewLoad(tp, ptr), nil
+		}
+	}
+	re, err := implicitCast(v, target, s)
+	if err != nil {
+		return nil, fmt.Errorf("cannot convert %v to %v", v.Type(), target)
+	}
+	return re, nil
+}
+
+// sameUnderlying reports whether a and b have identical memory layout
+func sameUnderlying(a, b types.Type) bool {
+	if a.Equal(b) {
+		return true
+	}
+	switch a := a.(type) {
+	case *types.StructType:
+		b, ok := b.(*types.StructType)
+		if !ok || a.Opaque || b.Opaque || len(a.Fields) != len(b.Fields) {
+			return false
+		}
+		for i, f := range a.Fields {
+			if !f.Equal(b.Fields[i]) {
+				return false
+			}
+		}
+		return true
+	case *types.PointerType:
+		b, ok := b.(*types.PointerType)
+		return ok && sameUnderlying(a.ElemType, b.ElemType)
+	case *types.FuncType:
+		b, ok := b.(*types.FuncType)
+		if !ok || len(a.Params) != len(b.Params) || !sameUnderlying(a.RetType, b.RetType) {
+			return false
+		}
+		for i, p := range a.Params {
+			if !sameUnderlying(p, b.Params[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
+
+// isByteSlice reports whether tp is []byte
+func isByteSlice(tp types.Type, s *Scope) bool {
+	if p, ok := tp.(*types.PointerType); ok {
+		tp = p.ElemType
+	}
+	if _, ok := tp.(*types.StructType); !ok {
+		return false
+	}
+	gnf := ScopeMap[SLICE].getGenericStruct("Slice")
+	return tp.Equal(gnf(s.m, &calcedTypeNode{uint8Type}).structType)
+}
@@ -0,0 +1,206 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Chronostasys/calc/compiler/helper"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// maxEmbedDepth limits the search of promoted fields and methods, it
+// also stops recursive embedding through pointers
+const maxEmbedDepth = 8
+
+// embedWrappers caches the generated promoted methods by full name
+var embedWrappers = map[string]*ir.Func{}
+
+// structDef returns the typedef of the struct type tp (or pointer to it),
+// nil if tp is not a named struct
+func structDef(tp types.Type, s *Scope) *typedef {
+	name := getTypeName(tp)
+	gen := ""
+	if idx := strings.Index(name, "<"); idx > -1 {
+		name, gen = name[:idx], name[idx:]
+	}
+	ss := helper.SplitLast(name, ".")
+	scope := s
+	if len(ss) > 1 {
+		scope = ScopeMap[ss[0]]
+		if scope == nil {
+			return nil
+		}
+		return scope.getStruct(ss[1] + gen)
+	}
+	return scope.getStruct(ss[0] + gen)
+}
+
+// embeddedFields returns the embedded fields of td in declaration order
+func embeddedFields(td *typedef) []*field {
+	fs := []*field{}
+	for _, f := range td.fieldsIdx {
+		if f.embedded {
+			fs = append(fs, f)
+		}
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		return fs[i].idx < fs[j].idx
+	})
+	return fs
+}
+
+type embedNode struct {
+	td   *typedef
+	path []*field
+}
+
+// walkEmbedded visits the embedded fields of td level by level, so that
+// shallower ones hide deeper ones like in go. It stops once f returns true.
+func walkEmbedded(td *typedef, s *Scope, f func(path []*field) bool) {
+	level := []embedNode{{td, nil}}
+	for depth := 0; len(level) > 0 && depth < maxEmbedDepth; depth++ {
+		next := []embedNode{}
+		for _, n := range level {
+			for _, fi := range embeddedFields(n.td) {
+				path := append(n.path[:len(n.path):len(n.path)], fi)
+				if f(path) {
+					return
+				}
+				if inner := structDef(fi.ftype, s); inner != nil {
+					next = append(next, embedNode{inner, path})
+				}
+			}
+		}
+		level = next
+	}
+}
+
+// fieldPath finds field name of td, which may be promoted from embedded
+// fields. It returns the fields to go through, nil if not found.
+func fieldPath(td *typedef, name string, s *Scope) []*field {
+	if fi, ok := td.fieldsIdx[name]; ok {
+		return []*field{fi}
+	}
+	var re []*field
+	walkEmbedded(td, s, func(path []*field) bool {
+		inner := structDef(path[len(path)-1].ftype, s)
+		if inner == nil {
+			return false
+		}
+		if fi, ok := inner.fieldsIdx[name]; ok {
+			re = append(path, fi)
+			return true
+		}
+		return false
+	})
+	return re
+}
+
+// gepPath returns the pointer to the last field of path. va points to a
+// struct of type st, embedded pointers on the way are loaded.
+func gepPath(b *ir.Block, st types.Type, va value.Value, path []*field) value.Value {
+	for i, fi := range path {
+		va = b.NewGetElementPtr(st, va, zero, constant.NewInt(types.I32, int64(fi.idx)))
+		st = fi.ftype
+		if p, ok := st.(*types.PointerType); ok && i < len(path)-1 {
+			va = b.NewLoad(p, va)
+			st = p.ElemType
+		}
+	}
+	return va
+}
+
+// methodOf looks up the extension method name of type tp
+func methodOf(tp types.Type, name string, s *Scope) *ir.Func {
+	td := structDef(tp, s)
+	if td == nil {
+		return nil
+	}
+	full := getTypeName(tp)
+	if idx := strings.Index(full, "<"); idx > -1 {
+		full = full[:idx]
+	}
+	scope := s
+	if ss := helper.SplitLast(full, "."); len(ss) > 1 && ScopeMap[ss[0]] != nil {
+		scope = ScopeMap[ss[0]]
+	}
+	scope.paramGenerics = [][]types.Type{td.generics}
+	v, err := scope.searchVar(full + "." + name)
+	if err != nil {
+		return nil
+	}
+	fn, _ := v.v.(*ir.Func)
+	return fn
+}
+
+// promotedMethod finds method name in the embedded fields of tp, and
+// returns a wrapper of it whose receiver is tp
+func promotedMethod(tp types.Type, name string, s *Scope) *ir.Func {
+	td := structDef(tp, s)
+	if td == nil {
+		return nil
+	}
+	key := strings.Replace(getTypeName(td.structType), "\\22", "\"", -1) + "." + name
+	if w, ok := embedWrappers[key]; ok {
+		return w
+	}
+	var re *ir.Func
+	walkEmbedded(td, s, func(path []*field) bool {
+		fn := methodOf(path[len(path)-1].ftype, name, s)
+		if fn == nil {
+			return false
+		}
+		re = embedWrapper(s.m, key, td.structType, path, fn)
+		embedWrappers[key] = re
+		return true
+	})
+	return re
+}
+
+// embedWrapper generates method name of outer, which calls fn with the
+// embedded field at path as receiver
+func embedWrapper(m *ir.Module, name string, outer types.Type, path []*field, fn *ir.Func) *ir.Func {
+	_, ptrRecv := fn.Sig.Params[0].(*types.PointerType)
+	recv := outer
+	if ptrRecv {
+		recv = types.NewPointer(outer)
+	}
+	ps := []*ir.Param{ir.NewParam("this", recv)}
+	for i, p := range fn.Sig.Params[1:] {
+		ps = append(ps, ir.NewParam(fmt.Sprintf("p%d", i), p))
+	}
+	w := m.NewFunc(name, fn.Sig.RetType, ps...)
+	if variadicFuncs[fn.Sig] {
+		variadicFuncs[w.Sig] = true
+	}
+	b := w.NewBlock("")
+	var va value.Value = ps[0]
+	if !ptrRecv {
+		va = b.NewAlloca(outer)
+		b.NewStore(ps[0], va)
+	}
+	va = gepPath(b, outer, va, path)
+	inner := path[len(path)-1].ftype
+	if p, ok := inner.(*types.PointerType); ok {
+		va = b.NewLoad(p, va)
+		inner = p.ElemType
+	}
+	args := []value.Value{va}
+	if !ptrRecv {
+		args[0] = b.NewLoad(inner, va)
+	}
+	for _, p := range ps[1:] {
+		args = append(args, p)
+	}
+	re := b.NewCall(fn, args...)
+	if fn.Sig.RetType.Equal(types.Void) {
+		b.NewRet(nil)
+	} else {
+		b.NewRet(re)
+	}
+	return w
+}
@@ -0,0 +1,74 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+)
+
+// enumTypes records the members of every enum type by its full name,
+// switch statements use it to check exhaustiveness
+var enumTypes = map[string][]string{}
+
+// EnumDefNode enum type, lowered to a named int. Members become typed
+// constants numbered from zero, like a const block using iota.
+type EnumDefNode struct {
+	ptrlevel int
+	Members  []string
+}
+
+func (n *EnumDefNode) Clone() TypeNode {
+	return &EnumDefNode{
+		n.ptrlevel, n.Members,
+	}
+}
+
+func (n *EnumDefNode) GetPtrLevel() int {
+	return n.ptrlevel
+}
+
+func (n *EnumDefNode) SetPtrLevel(i int) {
+	n.ptrlevel = i
+}
+
+func (n *EnumDefNode) calc(s *Scope) (types.Type, error) {
+	if n.ptrlevel > 0 {
+		return nil, fmt.Errorf("enum type cannot be a pointer")
+	}
+	return lexer.DefaultIntType(), nil
+}
+
+func (n *EnumDefNode) String(*Scope) string {
+	panic("not impl")
+}
+
+// define adds the member constants and the generated String method of enum
+// type id to the global scope
+func (n *EnumDefNode) define(m *ir.Module, s *Scope, id string, tp *types.IntType) {
+	name := s.getFullName(id)
+	enumTypes[name] = n.Members
+
+	p := ir.NewParam("e", tp)
+	fn := m.NewFunc(s.getFullName(id+".String"), getstrtp(), p)
+	entry := fn.NewBlock("")
+	def := fn.NewBlock("default")
+	def.NewRet(constStr(m, id+"(?)"))
+	cases := []*ir.Case{}
+	for i, k := range n.Members {
+		c := constant.NewInt(tp, int64(i))
+		if err := s.globalScope.addVar(k, &variable{v: c}); err != nil {
+			// members share the package scope, a taken name would keep
+			// its old value
+			fmt.Printf("\033[31m[error]\033[0m: enum member %s of %s is already defined in package %s\n", k, id, s.Pkgname)
+			errn++
+		}
+		b := fn.NewBlock(k)
+		b.NewRet(constStr(m, k))
+		cases = append(cases, ir.NewCase(c, b))
+	}
+	entry.NewSwitch(p, def, cases...)
+	s.globalScope.addVar(id+".String", &variable{v: fn})
+}
@@ -0,0 +1,110 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// the kinds returned by kindof<T>, they must be the same as the ones in
+// runtime/fmt
+const (
+	kindOther = iota
+	kindInt
+	kindUint
+	kindFloat
+	kindBool
+	kindString
+	kindPointer
+)
+
+func typeKind(tp types.Type) int64 {
+	switch t := tp.(type) {
+	case *types.IntType:
+		if t.BitSize == 1 {
+			return kindBool
+		}
+		if isUnsigned(t) {
+			return kindUint
+		}
+		return kindInt
+	case *types.FloatType:
+		return kindFloat
+	case *types.PointerType:
+		return kindPointer
+	}
+	if isStrType(tp) {
+		return kindString
+	}
+	return kindOther
+}
+
+// kindFunc returns kindof<T>, which returns the kind of tp
+func kindFunc(m *ir.Module, tp types.Type, s *Scope) value.Value {
+	fnname := s.getFullName(fmt.Sprintf("kindof<%s>", typeSig(tp)))
+	fn, err := s.globalScope.searchVar(fnname)
+	if err != nil {
+		f := m.NewFunc(fnname, lexer.DefaultIntType())
+		f.NewBlock("").NewRet(constant.NewInt(lexer.DefaultIntType(), typeKind(tp)))
+		fn = &variable{v: f}
+		s.globalScope.addVar(f.Name(), fn)
+	}
+	return fn.v
+}
+
+// toInterfaceFunc returns tointerface<T,I>(v T, ok *bool) I, which converts
+// v to interface it. If tp does not implement it, ok is set to false and
+// the zero interface is returned, so generic code can check at compile
+// time whether a type has some methods.
+func toInterfaceFunc(m *ir.Module, tp types.Type, it types.Type, s *Scope) value.Value {
+	fnname := s.getFullName(fmt.Sprintf("tointerface<%s,%s>", typeSig(tp), typeSig(it)))
+	fn, err := s.globalScope.searchVar(fnname)
+	if err == nil {
+		return fn.v
+	}
+	p := ir.NewParam("v", tp)
+	ok := ir.NewParam("ok", types.NewPointer(types.I1))
+	f := m.NewFunc(fnname, it, p, ok)
+	fs := s.globalScope.addChildScope(f.NewBlock(""))
+	fs.m = m
+	var v value.Value
+	func() {
+		defer func() {
+			if recover() != nil {
+				v = nil
+			}
+		}()
+		if v, err = implicitCast(p, it, fs); err != nil {
+			v = nil
+		}
+	}()
+	if v == nil {
+		// drop the instructions of the failed cast
+		f.Blocks = nil
+		b := f.NewBlock("")
+		b.NewStore(constant.False, ok)
+		b.NewRet(constant.NewZeroInitializer(it))
+	} else {
+		fs.block.NewStore(constant.True, ok)
+		fs.block.NewRet(v)
+	}
+	fn = &variable{v: f}
+	s.globalScope.addVar(f.Name(), fn)
+	return fn.v
+}
+
+// isFmtArg reports whether tp is fmt.Arg, which any value can be passed as
+func isFmtArg(tp types.Type) bool {
+	_, ok := tp.(*types.StructType)
+	return ok && getTypeName(tp) == FMT_MOD+".Arg"
+}
+
+// fmtArg boxes v into a fmt.Arg by fmt.ArgOf<T>, nil is a nil *byte
+func fmtArg(v value.Value, s *Scope) value.Value {
+	argOf := ScopeMap[FMT_MOD].getGenericFunc("ArgOf")(s.m, &calcedTypeNode{v.Type()})
+	return s.block.NewCall(argOf, v)
+}
@@ -14,9 +14,10 @@ import (
 )
 
 type ParamNode struct {
-	ID  string
-	TP  TypeNode
-	Val value.Value
+	ID       string
+	TP       TypeNode
+	Val      value.Value
+	Variadic bool
 }
 
 func (n *ParamNode) travel(f func(Node) bool) {
@@ -36,8 +37,10 @@ func (n *ParamNode) V() value.Value {
 }
 
 type ParamsNode struct {
-	Params []*ParamNode
-	Ext    bool
+	Params    []*ParamNode
+	Ext       bool
+	Variadic  bool // 最后一个参数是...T
+	CVariadic bool // c的可变参数，只能用于外部函数
 }
 
 func (n *ParamsNode) travel(f func(Node) bool) {
@@ -128,7 +131,7 @@ func (n *FuncNode) AddtoScope(s *Scope) {
 					tp, _ = gens[i].calc(s)
 				}
 				s.genericMap[v] = tp
-				sig += tp.String() + ","
+				sig += typeSig(tp) + ","
 			}
 			gen1 := make(map[string]types.Type)
 			for k, v := range s.genericMap {
@@ -148,6 +151,7 @@ func (n *FuncNode) AddtoScope(s *Scope) {
 			}
 
 			fun := m.NewFunc(s.getFullName(sig), tp, ps...)
+			markVariadic(fun.Sig, psn)
 			gs := s.generics
 			defer func() {
 				s.generics = gs
@@ -198,7 +202,9 @@ func (n *FuncNode) AddtoScope(s *Scope) {
 				fullname = s.getFullName(n.ID)
 			}
 			asyncFunc[s.getFullName(n.ID)] = n.Async
-			s.globalScope.addVar(n.ID, &variable{v: ir.NewFunc(fullname, tp, ps...)})
+			fn := ir.NewFunc(fullname, tp, ps...)
+			markVariadic(fn.Sig, psn)
+			s.globalScope.addVar(n.ID, &variable{v: fn})
 		})
 	}
 }
@@ -448,10 +454,12 @@ func (n *FuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	}
 	// only declaration
 	if n.Statements == nil {
-
-		return m.NewFunc(n.ID, tp, ps...)
+		fn := m.NewFunc(n.ID, tp, ps...)
+		markVariadic(fn.Sig, psn)
+		return fn
 	}
 	fn := m.NewFunc(s.getFullName(n.ID), tp, ps...)
+	markVariadic(fn.Sig, psn)
 	b := fn.NewBlock("")
 	childScope := s.addChildScope(b)
 	childScope.freeFunc = nil
@@ -489,6 +497,7 @@ type CallFuncNode struct {
 	parent   value.Value
 	Next     Node
 	Generics []TypeNode
+	Spread   bool // f(xs...)
 }
 
 func (n *CallFuncNode) tp() TypeNode {
@@ -506,7 +515,52 @@ func (n *CallFuncNode) travel(f func(Node) bool) {
 	n.FnNode.travel(f)
 }
 
+// convNode returns the conversion if n is a conversion to a named type
+// like MyInt(x), which looks the same as a function call
+func (n *CallFuncNode) convNode(s *Scope) *ConvertNode {
+	varNode, ok := n.FnNode.(*VarBlockNode)
+	if !ok || len(n.Params) != 1 || len(n.Generics) > 0 || n.parent != nil || len(varNode.Idxs) > 0 {
+		return nil
+	}
+	tp := &BasicTypeNode{CustomTp: []string{varNode.Token}, Pkg: s.Pkgname}
+	sc := s
+	if varNode.Next != nil {
+		sc = ScopeMap[varNode.Token]
+		if sc == nil || varNode.Next.Next != nil || len(varNode.Next.Idxs) > 0 {
+			return nil
+		}
+		tp = &BasicTypeNode{CustomTp: []string{varNode.Token, varNode.Next.Token}}
+	}
+	token := tp.CustomTp[len(tp.CustomTp)-1]
+	if _, err := sc.searchVar(token); err == nil || sc.getStruct(token) == nil {
+		return nil
+	}
+	return &ConvertNode{TP: tp, Val: n.Params[0].(ExpNode)}
+}
+
 func (n *CallFuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	if re, ok := n.builtin(m, f, s); ok {
+		return re
+	}
+	if conv := n.convNode(s); conv != nil {
+		re := conv.calc(m, f, s)
+		if n.Next == nil {
+			return re
+		}
+		if _, ok := re.Type().(*types.PointerType); !ok {
+			alloc := stackAlloc(s.m, s, re.Type())
+			store(re, alloc, s)
+			re = alloc
+		}
+		re = deReference(re, s)
+		switch next := n.Next.(type) {
+		case *CallFuncNode:
+			next.parent = re
+		case *VarBlockNode:
+			next.parent = re
+		}
+		return n.Next.calc(m, f, s)
+	}
 	var fn value.Value
 	var fntp *types.FuncType
 
@@ -558,6 +612,10 @@ func (n *CallFuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 		} else {
 			alloca = n.parent
 		}
+		if td := structDef(alloca.Type(), s); td != nil && len(td.generics) > 0 {
+			// the generics of methods are decided by the receiver type
+			paramGenerics[0] = td.generics
+		}
 		name := strings.Trim(alloca.Type().String(), "%*\"")
 		oris := name
 		idx := strings.Index(name, "<")
@@ -607,6 +665,8 @@ func (n *CallFuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 
 			ft := types.NewFunc(ret, ps...)
 			fnv = s.block.NewIntToPtr(loadIfVar(interger, s), types.NewPointer(ft))
+			// the methods of zeroed interfaces are nil
+			checkNilDesc(s, fnv, "call of "+fnNode.Token+" on nil interface at "+fnNode.pos())
 
 		} else if len(n.Generics) > 0 {
 			if gfn := scope.getGenericFunc(name); gfn != nil {
@@ -634,11 +694,17 @@ func (n *CallFuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 				idx := st.fieldsIdx[sse]
 				va = &variable{}
 				err = nil
-				if idx == nil {
+				if idx != nil {
+					va.v = s.block.NewGetElementPtr(st.structType, alloca, zero, constant.NewInt(types.I32, int64(idx.idx)))
+					member = true
+				} else if pm := promotedMethod(alloca.Type(), sse, s); pm != nil {
+					va.v = pm
+				} else if path := fieldPath(st, sse, s); path != nil {
+					va.v = gepPath(s.block, st.structType, alloca, path)
+					member = true
+				} else {
 					fnNode.err()
 				}
-				va.v = s.block.NewGetElementPtr(st.structType, alloca, zero, constant.NewInt(types.I32, int64(idx.idx)))
-				member = true
 			}
 			fnv = va.v
 			if err != nil {
@@ -682,7 +748,12 @@ func (n *CallFuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 			fntp = loadElmType(fn.Type()).(*types.FuncType)
 		}
 	}
+	pvs = variadicArgs(fntp, pvs, poff, n.Spread, s)
 	for i, v := range pvs {
+		if i+poff >= len(fntp.Params) {
+			params = append(params, v)
+			continue
+		}
 		tp := fntp.Params[i+poff]
 		v1 := v
 		p, err := implicitCast(v1, tp, s)
@@ -709,6 +780,7 @@ func (n *CallFuncNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 		re = alloc
 	}
 	if n.Next != nil {
+		s.nilExp, s.nilExpPath = fnNode, "()"
 		re = deReference(re, s)
 		switch next := n.Next.(type) {
 		case *CallFuncNode:
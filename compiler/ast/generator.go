@@ -34,6 +34,9 @@ func (c *ctx) setVals(st value.Value, s *Scope) {
 	}
 }
 
+// probeModules are the throwaway modules buildCtx calculates types in
+var probeModules = map[*ir.Module]bool{}
+
 func buildCtx(sl *SLNode, s *Scope, tps []types.Type, ps []*ir.Param) ([]types.Type, *ctx) {
 	mvart := map[string]map[string]*variable{}
 	for k, v := range ScopeMap {
@@ -44,6 +47,13 @@ func buildCtx(sl *SLNode, s *Scope, tps []types.Type, ps []*ir.Param) ([]types.T
 	}
 	defer func() {
 		for k, v := range ScopeMap {
+			// generic functions instantiated in a real module while building
+			// the context are kept, or they would be defined again later
+			for k2, v2 := range v.vartable {
+				if fn, ok := v2.v.(*ir.Func); ok && !probeModules[fn.Parent] && mvart[k][k2] == nil {
+					mvart[k][k2] = v2
+				}
+			}
 			v.vartable = mvart[k]
 		}
 		s.childrenScopes = nil
@@ -51,11 +61,14 @@ func buildCtx(sl *SLNode, s *Scope, tps []types.Type, ps []*ir.Param) ([]types.T
 	c := &ctx{idxmap: []*ctx{}}
 	var trf func(n Node)
 	tpm := ir.NewModule()
+	probeModules[tpm] = true
 	tpf := tpm.NewFunc("xxxx", types.Void)
 	tpsc := newScope(tpf.NewBlock(""))
 	tpsc.Pkgname = s.Pkgname
 	for _, v := range ps {
-		tpsc.addVar(v.LocalName, &variable{v: v})
+		// params are fields of the context in the generated function, so
+		// they are pointers here too
+		tpsc.addVar(v.LocalName, &variable{v: stackAlloc(tpm, tpsc, v.Type())})
 	}
 	tpsc.globalScope = s.globalScope
 	tpsc.parent = s.parent
@@ -90,6 +103,17 @@ func buildCtx(sl *SLNode, s *Scope, tps []types.Type, ps []*ir.Param) ([]types.T
 			ct.id = c.i
 			c.idxmap = append(c.idxmap, ct)
 			c.i++
+		case *SwitchNode:
+			for _, st := range node.bodies() {
+				ntps, ct := buildCtx(st.(*SLNode), tpsc.addChildScope(tpf.NewBlock("")), []types.Type{}, ps)
+				tps = append(tps, types.NewStruct(ntps...))
+				ct.father = c
+				ct.id = c.i
+				c.idxmap = append(c.idxmap, ct)
+				c.i++
+			}
+		case *ConstNode:
+			node.calc(tpm, tpf, tpsc)
 		case *InlineFuncNode:
 			ntps, ct := buildCtx(node.Body.(*SLNode), tpsc, []types.Type{}, ps)
 			tps = append(tps, types.NewStruct(ntps...))
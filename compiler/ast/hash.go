@@ -0,0 +1,225 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// hashType is the result type of hashof<T>
+var hashType = newUnsigned(64)
+
+// hashFuncs and equalFuncs cache the generated functions by type signature
+var (
+	hashFuncs  = map[string]*ir.Func{}
+	equalFuncs = map[string]*ir.Func{}
+)
+
+func u64(x uint64) *constant.Int {
+	return constant.NewInt(types.I64, int64(x))
+}
+
+// mix64 is the finalizer of splitmix64, it spreads the bits of ints and
+// pointers, so that they do not fall into the same buckets
+func mix64(b *ir.Block, h value.Value) value.Value {
+	h = b.NewXor(h, b.NewLShr(h, u64(33)))
+	h = b.NewMul(h, u64(0xff51afd7ed558ccd))
+	h = b.NewXor(h, b.NewLShr(h, u64(33)))
+	h = b.NewMul(h, u64(0xc4ceb9fe1a85ec53))
+	return b.NewXor(h, b.NewLShr(h, u64(33)))
+}
+
+func isStrType(tp types.Type) bool {
+	_, ok := tp.(*types.StructType)
+	return ok && getTypeName(tp) == STRINGS+"._str"
+}
+
+// structFields returns the field types of struct tp in layout order, and
+// the struct type with its body. Named struct types may be created without
+// fields (e.g. string), so their body is taken from the typedef.
+func structFields(tp *types.StructType, s *Scope) (types.Type, []types.Type) {
+	if tp.TypeName == "" {
+		return tp, tp.Fields
+	}
+	td := structDef(tp, s)
+	if td == nil {
+		panic(fmt.Errorf("cannot find struct %s", tp.Name()))
+	}
+	fs := make([]*field, 0, len(td.fieldsIdx))
+	for _, f := range td.fieldsIdx {
+		fs = append(fs, f)
+	}
+	sort.Slice(fs, func(i, j int) bool {
+		return fs[i].idx < fs[j].idx
+	})
+	re := make([]types.Type, len(fs))
+	for i, f := range fs {
+		re[i] = f.ftype
+	}
+	return td.structType, re
+}
+
+// loopN generates `for i := 0; i < n; i++ { body }` at the end of b. body
+// gets the block to append to and i, it returns the block the loop
+// continues from. The block after the loop is returned.
+func loopN(f *ir.Func, b *ir.Block, n value.Value, body func(b *ir.Block, i value.Value) *ir.Block) *ir.Block {
+	ip := f.Blocks[0].NewAlloca(n.Type())
+	b.NewStore(constant.NewInt(n.Type().(*types.IntType), 0), ip)
+	cond := f.NewBlock("")
+	b.NewBr(cond)
+	loop := f.NewBlock("")
+	end := f.NewBlock("")
+	i := cond.NewLoad(n.Type(), ip)
+	cond.NewCondBr(cond.NewICmp(enum.IPredSLT, i, n), loop, end)
+	last := body(loop, i)
+	last.NewStore(last.NewAdd(i, constant.NewInt(n.Type().(*types.IntType), 1)), ip)
+	last.NewBr(cond)
+	return end
+}
+
+// hashFunc returns the hash function of tp, which is used by the map
+// runtime through hashof<T>. Ints, floats, pointers, strings and arrays or
+// structs of them are supported.
+func hashFunc(m *ir.Module, tp types.Type, s *Scope) *ir.Func {
+	name := fmt.Sprintf("hashof<%s>", typeSig(tp))
+	if f, ok := hashFuncs[name]; ok {
+		return f
+	}
+	p := ir.NewParam("v", tp)
+	f := m.NewFunc(name, hashType, p)
+	hashFuncs[name] = f
+	b := f.NewBlock("")
+	switch t := tp.(type) {
+	case *types.IntType:
+		var h value.Value = p
+		if t.BitSize < 64 {
+			h = b.NewZExt(p, types.I64)
+		}
+		b.NewRet(mix64(b, h))
+	case *types.FloatType:
+		// +0 and -0 are equal, so they must have the same hash
+		var v value.Value = b.NewSelect(b.NewFCmp(enum.FPredOEQ, p, constant.NewFloat(t, 0)), constant.NewFloat(t, 0), p)
+		if t.Kind == types.FloatKindDouble {
+			v = b.NewBitCast(v, types.I64)
+		} else {
+			v = b.NewZExt(b.NewBitCast(v, types.I32), types.I64)
+		}
+		b.NewRet(mix64(b, v))
+	case *types.PointerType:
+		b.NewRet(mix64(b, b.NewPtrToInt(p, types.I64)))
+	case *types.ArrayType:
+		a := b.NewAlloca(t)
+		b.NewStore(p, a)
+		hp := b.NewAlloca(types.I64)
+		b.NewStore(u64(17), hp)
+		end := loopN(f, b, constant.NewInt(types.I32, int64(t.Len)), func(b *ir.Block, i value.Value) *ir.Block {
+			e := b.NewLoad(t.ElemType, b.NewGetElementPtr(t, a, zero, i))
+			h := b.NewMul(b.NewLoad(types.I64, hp), u64(31))
+			b.NewStore(b.NewAdd(h, b.NewCall(hashFunc(m, t.ElemType, s), e)), hp)
+			return b
+		})
+		end.NewRet(end.NewLoad(types.I64, hp))
+	case *types.StructType:
+		st, fts := structFields(t, s)
+		a := b.NewAlloca(t)
+		b.NewStore(p, a)
+		if isStrType(t) {
+			// FNV-1a
+			bs := b.NewLoad(types.I8Ptr, b.NewGetElementPtr(st, a, zero, zero))
+			l := b.NewLoad(lexer.DefaultIntType(), b.NewGetElementPtr(st, a, zero, constant.NewInt(types.I32, 1)))
+			hp := b.NewAlloca(types.I64)
+			b.NewStore(u64(14695981039346656037), hp)
+			end := loopN(f, b, l, func(b *ir.Block, i value.Value) *ir.Block {
+				ch := b.NewZExt(b.NewLoad(types.I8, b.NewGetElementPtr(types.I8, bs, i)), types.I64)
+				h := b.NewXor(b.NewLoad(types.I64, hp), ch)
+				b.NewStore(b.NewMul(h, u64(1099511628211)), hp)
+				return b
+			})
+			end.NewRet(end.NewLoad(types.I64, hp))
+			break
+		}
+		var h value.Value = u64(17)
+		for i, ft := range fts {
+			e := b.NewLoad(ft, b.NewGetElementPtr(st, a, zero, constant.NewInt(types.I32, int64(i))))
+			h = b.NewAdd(b.NewMul(h, u64(31)), b.NewCall(hashFunc(m, ft, s), e))
+		}
+		b.NewRet(h)
+	default:
+		panic(fmt.Errorf("invalid map key type %s", tp))
+	}
+	return f
+}
+
+// equalFunc returns the function comparing two values of tp, it supports
+// the same types as hashFunc
+func equalFunc(m *ir.Module, tp types.Type, s *Scope) *ir.Func {
+	name := fmt.Sprintf("equals<%s>", typeSig(tp))
+	if f, ok := equalFuncs[name]; ok {
+		return f
+	}
+	p1, p2 := ir.NewParam("a", tp), ir.NewParam("b", tp)
+	f := m.NewFunc(name, types.I1, p1, p2)
+	equalFuncs[name] = f
+	b := f.NewBlock("")
+	// ne is the block returning false
+	ne := f.NewBlock("")
+	ne.NewRet(constant.False)
+	switch t := tp.(type) {
+	case *types.IntType, *types.PointerType:
+		b.NewRet(b.NewICmp(enum.IPredEQ, p1, p2))
+	case *types.FloatType:
+		b.NewRet(b.NewFCmp(enum.FPredOEQ, p1, p2))
+	case *types.ArrayType:
+		a1, a2 := b.NewAlloca(t), b.NewAlloca(t)
+		b.NewStore(p1, a1)
+		b.NewStore(p2, a2)
+		end := loopN(f, b, constant.NewInt(types.I32, int64(t.Len)), func(b *ir.Block, i value.Value) *ir.Block {
+			e1 := b.NewLoad(t.ElemType, b.NewGetElementPtr(t, a1, zero, i))
+			e2 := b.NewLoad(t.ElemType, b.NewGetElementPtr(t, a2, zero, i))
+			next := f.NewBlock("")
+			b.NewCondBr(b.NewCall(equalFunc(m, t.ElemType, s), e1, e2), next, ne)
+			return next
+		})
+		end.NewRet(constant.True)
+	case *types.StructType:
+		st, fts := structFields(t, s)
+		a1, a2 := b.NewAlloca(t), b.NewAlloca(t)
+		b.NewStore(p1, a1)
+		b.NewStore(p2, a2)
+		if isStrType(t) {
+			l1 := b.NewLoad(lexer.DefaultIntType(), b.NewGetElementPtr(st, a1, zero, constant.NewInt(types.I32, 1)))
+			l2 := b.NewLoad(lexer.DefaultIntType(), b.NewGetElementPtr(st, a2, zero, constant.NewInt(types.I32, 1)))
+			bs1 := b.NewLoad(types.I8Ptr, b.NewGetElementPtr(st, a1, zero, zero))
+			bs2 := b.NewLoad(types.I8Ptr, b.NewGetElementPtr(st, a2, zero, zero))
+			start := f.NewBlock("")
+			b.NewCondBr(b.NewICmp(enum.IPredEQ, l1, l2), start, ne)
+			end := loopN(f, start, l1, func(b *ir.Block, i value.Value) *ir.Block {
+				c1 := b.NewLoad(types.I8, b.NewGetElementPtr(types.I8, bs1, i))
+				c2 := b.NewLoad(types.I8, b.NewGetElementPtr(types.I8, bs2, i))
+				next := f.NewBlock("")
+				b.NewCondBr(b.NewICmp(enum.IPredEQ, c1, c2), next, ne)
+				return next
+			})
+			end.NewRet(constant.True)
+			break
+		}
+		for i, ft := range fts {
+			idx := constant.NewInt(types.I32, int64(i))
+			e1 := b.NewLoad(ft, b.NewGetElementPtr(st, a1, zero, idx))
+			e2 := b.NewLoad(ft, b.NewGetElementPtr(st, a2, zero, idx))
+			next := f.NewBlock("")
+			b.NewCondBr(b.NewCall(equalFunc(m, ft, s), e1, e2), next, ne)
+			b = next
+		}
+		b.NewRet(constant.True)
+	default:
+		panic(fmt.Errorf("invalid map key type %s", tp))
+	}
+	return f
+}
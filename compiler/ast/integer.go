@@ -0,0 +1,79 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// unsignedTypes holds all unsigned int types. Ints in llvm have no sign,
+// so whether a value is unsigned is decided by the identity of its type.
+// Never use the shared types.I8 etc. for unsigned types.
+var unsignedTypes = map[*types.IntType]bool{}
+
+var uint8Type = newUnsigned(8) // byte is an alias of uint8
+
+func newUnsigned(bits uint64) *types.IntType {
+	tp := types.NewInt(bits)
+	unsignedTypes[tp] = true
+	return tp
+}
+
+func isUnsigned(tp types.Type) bool {
+	t, ok := tp.(*types.IntType)
+	return ok && unsignedTypes[t]
+}
+
+// typeSig is the name of tp in generic instance signatures. Unsigned ints
+// print the same as signed ones in llvm, but must not share instances.
+func typeSig(tp types.Type) string {
+	switch t := tp.(type) {
+	case *types.IntType:
+		if isUnsigned(t) && t.TypeName == "" {
+			return fmt.Sprintf("u%d", t.BitSize)
+		}
+	case *types.PointerType:
+		if t.TypeName == "" {
+			return typeSig(t.ElemType) + "*"
+		}
+	case *types.ArrayType:
+		if t.TypeName == "" {
+			return fmt.Sprintf("[%d x %s]", t.Len, typeSig(t.ElemType))
+		}
+	}
+	return tp.String()
+}
+
+// copyBasicType returns a new instance of int, float and named struct
+// types, so that typedefs like `type X uint8` do not rename the shared
+// types they are defined with
+func copyBasicType(tp types.Type) types.Type {
+	switch t := tp.(type) {
+	case *types.IntType:
+		if isUnsigned(t) {
+			return newUnsigned(t.BitSize)
+		}
+		return types.NewInt(t.BitSize)
+	case *types.FloatType:
+		return &types.FloatType{Kind: t.Kind}
+	case *types.StructType:
+		if t.TypeName != "" {
+			return types.NewStruct(t.Fields...)
+		}
+	}
+	return tp
+}
+
+// extInt widens v to tp, sign extended if v is signed
+func extInt(b *ir.Block, v value.Value, tp *types.IntType) value.Value {
+	if c, ok := v.(*constant.Int); ok {
+		return &constant.Int{Typ: tp, X: c.X}
+	}
+	if isUnsigned(v.Type()) || v.Type().(*types.IntType).BitSize == 1 {
+		return b.NewZExt(v, tp)
+	}
+	return b.NewSExt(v, tp)
+}
@@ -0,0 +1,122 @@
+package ast
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// methods returns the method names of t in layout order
+func (t *interf) methods() []string {
+	ids := make([]string, 0, len(t.interfaceFuncs))
+	for k := range t.interfaceFuncs {
+		ids = append(ids, k)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		return t.interfaceFuncs[ids[i]].i < t.interfaceFuncs[ids[j]].i
+	})
+	return ids
+}
+
+// methodSig calculates the param and return types of method k of t, the
+// generic params of t are applied
+func (t *interf) methodSig(k string, s *Scope) ([]types.Type, types.Type, error) {
+	if len(t.genericMaps) > 0 {
+		old := s.genericMap
+		gm := map[string]types.Type{}
+		for k, v := range old {
+			gm[k] = v
+		}
+		for k, v := range t.genericMaps {
+			gm[k] = v
+		}
+		s.genericMap = gm
+		defer func() {
+			s.genericMap = old
+		}()
+	}
+	return funcNodeSig(t.interfaceFuncs[k], s)
+}
+
+func funcNodeSig(fn *FuncNode, s *Scope) ([]types.Type, types.Type, error) {
+	ps := []types.Type{}
+	for _, p := range fn.Params.Params {
+		tp, err := p.TP.calc(s)
+		if err != nil {
+			return nil, nil, err
+		}
+		ps = append(ps, tp)
+	}
+	ret, err := fn.RetType.calc(s)
+	return ps, ret, err
+}
+
+func sameSig(ps1 []types.Type, ret1 types.Type, ps2 []types.Type, ret2 types.Type) bool {
+	if len(ps1) != len(ps2) || !ret1.Equal(ret2) {
+		return false
+	}
+	for i, p := range ps1 {
+		if !p.Equal(ps2[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// sameMethod reports whether the interface methods a and b have the same
+// signature
+func sameMethod(a, b *FuncNode, s *Scope) bool {
+	ps1, ret1, err := funcNodeSig(a, s)
+	if err != nil {
+		return false
+	}
+	ps2, ret2, err := funcNodeSig(b, s)
+	if err != nil {
+		return false
+	}
+	return sameSig(ps1, ret1, ps2, ret2)
+}
+
+// castInterface converts interface value v to interface target.
+//
+// The method set of target must be a subset of the method set of v: every
+// method of target must exist in v with the same name, the same param types
+// and the same return type. Methods are matched by name, their order in the
+// two interfaces does not matter, and embedded methods count as the
+// interface's own.
+func castInterface(v value.Value, target *interf, s *Scope) (value.Value, error) {
+	src := v.Type().(*interf)
+	if src.id != "" && src.Equal(target) {
+		return v, nil
+	}
+	st := stackAlloc(s.m, s, target)
+	val := stackAlloc(s.m, s, src)
+	store(v, val, s)
+	for _, k := range target.methods() {
+		if _, ok := src.interfaceFuncs[k]; !ok {
+			return nil, fmt.Errorf("failed to cast %v to interface %v: missing method %s", src.Name(), target.Name(), k)
+		}
+		ps1, ret1, err := target.methodSig(k, s)
+		if err != nil {
+			return nil, err
+		}
+		ps2, ret2, err := src.methodSig(k, s)
+		if err != nil {
+			return nil, err
+		}
+		if !sameSig(ps1, ret1, ps2, ret2) {
+			return nil, fmt.Errorf("failed to cast %v to interface %v: method %s has a different signature", src.Name(), target.Name(), k)
+		}
+		f := s.block.NewGetElementPtr(target.Type, st, zero, constant.NewInt(types.I32, int64(target.interfaceFuncs[k].i)))
+		f2 := s.block.NewGetElementPtr(src.Type, val, zero, constant.NewInt(types.I32, int64(src.interfaceFuncs[k].i)))
+		store(loadIfVar(f2, s), f, s)
+	}
+	// the receiver
+	inst := s.block.NewGetElementPtr(src.Type, val, zero, zero)
+	ptr := s.block.NewGetElementPtr(target.Type, st, zero, zero)
+	store(loadIfVar(inst, s), ptr, s)
+	return loadIfVar(st, s), nil
+}
@@ -36,9 +36,11 @@ func (n *ForNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	body := f.NewBlock(strconv.Itoa(blockID))
 	blockID++
 	end := f.NewBlock(strconv.Itoa(blockID))
-	s.continueBlock = cond
-	s.breakBlock = end
+	// only the loop body can break and continue, statements after the
+	// loop still belong to the outer loop
 	child := s.addChildScope(body)
+	child.continueBlock = cond
+	child.breakBlock = end
 	condScope := s.addChildScope(cond)
 	name := ""
 	def := false
@@ -47,7 +49,7 @@ func (n *ForNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 		var n1 *DefAndAssignNode
 		n1, def = n.DefineAssign.(*DefAndAssignNode)
 		if def {
-			name = n1.ID
+			name = s.getFullName(n1.ID)
 		}
 	}
 	if n.Bool != nil {
@@ -0,0 +1,101 @@
+package ast
+
+import (
+	"strings"
+
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// MapTypeNode map[K]V, it is *maps.Map<K,V> in llvm
+type MapTypeNode struct {
+	Key      TypeNode
+	Val      TypeNode
+	PtrLevel int
+}
+
+func (n *MapTypeNode) Clone() TypeNode {
+	return &MapTypeNode{
+		n.Key, n.Val, n.PtrLevel,
+	}
+}
+
+func (n *MapTypeNode) GetPtrLevel() int {
+	return n.PtrLevel
+}
+
+func (n *MapTypeNode) SetPtrLevel(i int) {
+	n.PtrLevel = i
+}
+
+func (n *MapTypeNode) String(s *Scope) string {
+	t, err := n.calc(s.globalScope)
+	if err != nil {
+		panic(err)
+	}
+	return strings.Trim(t.String(), "%*\"")
+}
+
+func (n *MapTypeNode) calc(s *Scope) (types.Type, error) {
+	k, err := n.Key.calc(s)
+	if err != nil {
+		return nil, err
+	}
+	v, err := n.Val.calc(s)
+	if err != nil {
+		return nil, err
+	}
+	gnf := ScopeMap[MAPS].getGenericStruct("Map")
+	var tp types.Type = types.NewPointer(gnf(s.m, &calcedTypeNode{k}, &calcedTypeNode{v}).structType)
+	for i := 0; i < n.PtrLevel; i++ {
+		tp = types.NewPointer(tp)
+	}
+	return tp, nil
+}
+
+// MapInitNode map literal, like map[string]int{"a": 1}
+type MapInitNode struct {
+	Type *MapTypeNode
+	Keys []Node
+	Vals []Node
+}
+
+func (n *MapInitNode) tp() TypeNode {
+	return n.Type
+}
+
+func (n *MapInitNode) travel(f func(Node) bool) {
+	f(n)
+	for i := range n.Keys {
+		n.Keys[i].travel(f)
+		n.Vals[i].travel(f)
+	}
+}
+
+func (n *MapInitNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	tp, err := n.Type.calc(s)
+	if err != nil {
+		panic(err)
+	}
+	td := structDef(tp, s)
+	k, v := td.generics[0], td.generics[1]
+	newf := ScopeMap[MAPS].getGenericFunc("New")(m, &calcedTypeNode{k}, &calcedTypeNode{v})
+	mp := s.block.NewCall(newf)
+	set := methodOf(tp, INDEX_SET_RELOAD, s)
+	for i := range n.Keys {
+		kv, err := implicitCast(loadIfVar(n.Keys[i].calc(m, f, s), s), k, s)
+		if err != nil {
+			panic(err)
+		}
+		vv, err := implicitCast(loadIfVar(n.Vals[i].calc(m, f, s), s), v, s)
+		if err != nil {
+			panic(err)
+		}
+		s.block.NewCall(set, mp, kv, vv)
+	}
+	// pointers are taken as variables, so return the address of it
+	alloc := stackAlloc(m, s, tp)
+	store(mp, alloc, s)
+	return alloc
+}
@@ -0,0 +1,55 @@
+package ast
+
+import (
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// checkNilFunc panics if the pointer is nil
+var checkNilFunc *ir.Func
+
+// addNilFuncs defines checkNilFunc in m
+func addNilFuncs(m *ir.Module, printf, exit *ir.Func) {
+	checkNilFunc = checkFunc(m, printf, exit, "checkNil", "panic: runtime error: invalid memory address or nil pointer dereference",
+		func(b *ir.Block, ps []*ir.Param) value.Value {
+			return b.NewICmp(enum.IPredNE, ps[0], constant.NewNull(types.I8Ptr))
+		}, ir.NewParam("p", types.I8Ptr))
+}
+
+// nilExpDesc describes the last variable expression calculated in s
+func (s *Scope) nilExpDesc() string {
+	if s.nilExp == nil {
+		return "unknown expression"
+	}
+	return s.nilExp.Token + s.nilExpPath + " at " + s.nilExp.pos()
+}
+
+// checkNil checks the pointer v before it is dereferenced. Only pointers
+// loaded from memory can be nil, the addresses of variables and fields
+// are never checked.
+func checkNil(s *Scope, v value.Value) {
+	if _, ok := v.(*ir.InstLoad); !ok {
+		return
+	}
+	checkNilDesc(s, v, s.nilExpDesc())
+}
+
+// checkNilDesc checks the pointer v, desc is printed if it is nil. v is
+// checked once in each block of s
+func checkNilDesc(s *Scope, v value.Value, desc string) {
+	if s.nilChecked[v] == s.block {
+		return
+	}
+	if s.nilChecked == nil {
+		s.nilChecked = map[value.Value]*ir.Block{}
+	}
+	s.nilChecked[v] = s.block
+	p := v
+	if !v.Type().Equal(types.I8Ptr) {
+		p = s.block.NewBitCast(v, types.I8Ptr)
+	}
+	s.block.NewCall(checkNilFunc, p, posStr(s.m, desc))
+}
@@ -46,6 +46,7 @@ func (n *TakeValNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	v := n.Node.calc(m, f, s)
 
 	for i := 0; i < n.Level; i++ {
+		checkNil(s, v)
 		v = s.block.NewLoad(getElmType(v.Type()), v)
 	}
 	return v
@@ -34,6 +34,9 @@ type Scope struct {
 	paramGenerics  [][]types.Type
 	currParam      int
 	rightValue     value.Value
+	nilExp         *VarBlockNode // the last variable expression, named by nil checks
+	nilExpPath     string
+	nilChecked     map[value.Value]*ir.Block // the block each pointer is nil checked in
 	assigned       bool
 	freeFunc       func(*Scope)
 	yieldRet       value.Value
@@ -54,10 +57,13 @@ var externMap = map[string]bool{
 	"GC_debug_malloc":         true,
 	"GC_malloc_uncollectable": true,
 	"memcpy":                  true,
+	"memmove":                 true,
+	"memcmp":                  true,
 	"Sleep":                   true,
 	"llvm.init.trampoline":    true,
 	"llvm.adjust.trampoline":  true,
 	"malloc":                  true,
+	"exit":                    true,
 }
 
 func MergeGlobalScopes(ss ...*Scope) *Scope {
@@ -99,8 +105,9 @@ type typedef struct {
 }
 
 type field struct {
-	idx   int
-	ftype types.Type
+	idx      int
+	ftype    types.Type
+	embedded bool
 }
 
 func newScope(block *ir.Block) *Scope {
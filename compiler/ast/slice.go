@@ -0,0 +1,125 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// SliceExpNode slice expression x[Low:High], it is one of the Idxs of
+// VarBlockNode. Low and High may be nil.
+type SliceExpNode struct {
+	Low  Node
+	High Node
+}
+
+func (n *SliceExpNode) travel(f func(Node) bool) {
+	f(n)
+	if n.Low != nil {
+		n.Low.travel(f)
+	}
+	if n.High != nil {
+		n.High.travel(f)
+	}
+}
+
+func (n *SliceExpNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	panic(fmt.Errorf("slice expression must be used as an index"))
+}
+
+// slice calculates va[Low:High], va is a pointer to an array or a struct
+// with Slice and Len methods, like slices. It is x.Slice(Low, High), and
+// High defaults to x.Len(). vb is the expression sliced, which is used in
+// error messages.
+func (n *SliceExpNode) slice(m *ir.Module, f *ir.Func, s *Scope, va value.Value, vb *VarBlockNode) value.Value {
+	var c value.Value
+	l := int64(-1)
+	if atp, ok := va.Type().(*types.PointerType).ElemType.(*types.ArrayType); ok {
+		l = int64(atp.Len)
+		c = constant.NewInt(lexer.DefaultIntType(), l)
+		va = arrSlice(m, s, va)
+	}
+	method := func(name string) value.Value {
+		return loadIfVar(callMethod(m, f, s, va, &CallFuncNode{
+			FnNode: &VarBlockNode{Token: name},
+		}), s)
+	}
+	var low, high value.Value = constant.NewInt(lexer.DefaultIntType(), 0), nil
+	if n.Low != nil {
+		low = loadIfVar(n.Low.calc(m, f, s), s)
+	}
+	if n.High != nil {
+		high = loadIfVar(n.High.calc(m, f, s), s)
+	}
+	constLow := vb.constIndex(low, l, true)
+	constHigh := high != nil && vb.constIndex(high, l, true)
+	if constLow && constHigh && low.(*constant.Int).X.Cmp(high.(*constant.Int).X) > 0 {
+		panic(fmt.Errorf("invalid slice indices %v > %v (%s)", low.(*constant.Int).X, high.(*constant.Int).X, vb.pos()))
+	}
+	// x[:] and constant indices of arrays need no runtime checks
+	provable := constLow && (high == nil && (l >= 0 || low.(*constant.Int).X.Sign() == 0) ||
+		constHigh && l >= 0)
+	if high == nil {
+		if c != nil {
+			high = c
+		} else {
+			high = method("Len")
+		}
+	}
+	if BoundsCheck && !provable {
+		if c == nil && methodOf(va.Type(), "Cap", s) != nil {
+			c = method("Cap")
+		} else if c == nil {
+			c = method("Len")
+		}
+		checkSlice(m, s, low, high, c, vb.pos())
+	}
+	return callMethod(m, f, s, va, &CallFuncNode{
+		FnNode: &VarBlockNode{Token: "Slice"},
+		Params: []Node{&fakeNode{v: low}, &fakeNode{v: high}},
+	})
+}
+
+// arrSlice returns a slice sharing the memory of array va
+func arrSlice(m *ir.Module, s *Scope, va value.Value) value.Value {
+	atp := va.Type().(*types.PointerType).ElemType.(*types.ArrayType)
+	head := s.block.NewGetElementPtr(atp, va, zero, zero)
+	fn := ScopeMap[SLICE].getGenericFunc("FromArr")(m, &calcedTypeNode{atp.ElemType})
+	return s.block.NewCall(fn, head, constant.NewInt(types.I32, int64(atp.Len)))
+}
+
+// callMethod calls cf as a method of recv, recv must be a pointer to a
+// struct
+func callMethod(m *ir.Module, f *ir.Func, s *Scope, recv value.Value, cf *CallFuncNode) value.Value {
+	td := structDef(recv.Type(), s)
+	if td == nil {
+		panic(fmt.Errorf("%v has no method %s", recv.Type(), cf.FnNode.(*VarBlockNode).Token))
+	}
+	s.generics = td.generics
+	cf.parent = recv
+	return cf.calc(m, f, s)
+}
+
+// sliceInit creates the slice literal []T{...}, the elements are stored
+// in a new array on heap
+func (n *ArrayInitNode) sliceInit(m *ir.Module, f *ir.Func, s *Scope, tp types.Type) value.Value {
+	elm := structDef(tp, s).generics[0]
+	atp := types.NewArray(uint64(len(n.Vals)), elm)
+	arr := heapAlloc(m, s, &calcedTypeNode{atp})
+	for k, v := range n.Vals {
+		ptr := s.block.NewGetElementPtr(atp, arr, zero, constant.NewInt(types.I32, int64(k)))
+		cs, err := implicitCast(loadIfVar(v.calc(m, f, s), s), elm, s)
+		if err != nil {
+			panic(err)
+		}
+		store(cs, ptr, s)
+	}
+	// pointers are taken as variables, so return the address of it
+	alloc := stackAlloc(m, s, tp)
+	store(arrSlice(m, s, arr), alloc, s)
+	return alloc
+}
@@ -14,7 +14,7 @@ func AddSTDFunc(m *ir.Module, s *Scope) {
 	printf := m.NewFunc("printf", types.I32, ir.NewParam("formatstr", types.I8Ptr))
 	printf.Sig.Variadic = true
 	s.globalScope.addVar(printf.Name(), &variable{v: printf})
-	gi := m.NewGlobalDef("stri", constant.NewCharArrayFromString("%d\n\x00"))
+	gi := m.NewGlobalDef("stri", constant.NewCharArrayFromString("%lld\n\x00"))
 	p := ir.NewParam("i", lexer.DefaultIntType())
 	f := m.NewFunc("printIntln", types.Void, p)
 	b := f.NewBlock("")
@@ -60,11 +60,28 @@ func AddSTDFunc(m *ir.Module, s *Scope) {
 	f = m.NewFunc("memset", types.I8Ptr, p, ir.NewParam("v", lexer.DefaultIntType()), ir.NewParam("len", lexer.DefaultIntType()))
 	s.globalScope.addVar(f.Name(), &variable{v: f})
 
+	p = ir.NewParam("code", types.I32)
+	f = m.NewFunc("exit", types.Void, p)
+	s.globalScope.addVar(f.Name(), &variable{v: f})
+	addBoundsFuncs(m, printf, f)
+	addNilFuncs(m, printf, f)
+	addArithFuncs(m, printf, f)
+
 	p1 := ir.NewParam("dst", types.I8Ptr)
 	p2 := ir.NewParam("src", types.I8Ptr)
 	f = m.NewFunc("memcpy", types.I8Ptr, p1, p2, ir.NewParam("len", lexer.DefaultIntType()))
 	s.globalScope.addVar(f.Name(), &variable{v: f})
 
+	p1 = ir.NewParam("a", types.I8Ptr)
+	p2 = ir.NewParam("b", types.I8Ptr)
+	f = m.NewFunc("memcmp", types.I32, p1, p2, ir.NewParam("len", lexer.DefaultIntType()))
+	s.globalScope.addVar(f.Name(), &variable{v: f})
+
+	p1 = ir.NewParam("dst", types.I8Ptr)
+	p2 = ir.NewParam("src", types.I8Ptr)
+	f = m.NewFunc("memmove", types.I8Ptr, p1, p2, ir.NewParam("len", lexer.DefaultIntType()))
+	s.globalScope.addVar(f.Name(), &variable{v: f})
+
 	p1 = ir.NewParam("tramp", types.I8Ptr)
 	p2 = ir.NewParam("func", types.I8Ptr)
 	p3 := ir.NewParam("nval", types.I8Ptr)
@@ -82,7 +99,7 @@ func AddSTDFunc(m *ir.Module, s *Scope) {
 	s.globalScope.addGeneric("unsafecast", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
 		tpin, _ := gens[0].calc(s)
 		tpout, _ := gens[1].calc(s)
-		fnname := s.getFullName(fmt.Sprintf("unsafecast<%s,%s>", tpin.String(), tpout.String()))
+		fnname := s.getFullName(fmt.Sprintf("unsafecast<%s,%s>", typeSig(tpin), typeSig(tpout)))
 		fn, err := s.globalScope.searchVar(fnname)
 		if err != nil {
 			p = ir.NewParam("i", tpin)
@@ -134,7 +151,7 @@ func AddSTDFunc(m *ir.Module, s *Scope) {
 
 	s.globalScope.addGeneric("inttoptr", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
 		tp, _ := gens[0].calc(s)
-		fnname := s.getFullName(fmt.Sprintf("inttoptr<%s>", tp.String()))
+		fnname := s.getFullName(fmt.Sprintf("inttoptr<%s>", typeSig(tp)))
 		fn, err := s.globalScope.searchVar(fnname)
 		if err != nil {
 			p := ir.NewParam("int", lexer.DefaultIntType())
@@ -151,7 +168,7 @@ func AddSTDFunc(m *ir.Module, s *Scope) {
 
 	s.globalScope.addGeneric("_gep", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
 		tp, _ := gens[0].calc(s)
-		fnname := s.getFullName(fmt.Sprintf("_gep<%s>", tp.String()))
+		fnname := s.getFullName(fmt.Sprintf("_gep<%s>", typeSig(tp)))
 		fn, err := s.globalScope.searchVar(fnname)
 		if err != nil {
 			p := ir.NewParam("ptr", tp)
@@ -186,4 +203,27 @@ func AddSTDFunc(m *ir.Module, s *Scope) {
 
 	})
 
+	// hashof and equals are used by the map runtime
+	s.globalScope.addGeneric("hashof", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
+		tp, _ := gens[0].calc(s)
+		return hashFunc(m, tp, s)
+	})
+
+	s.globalScope.addGeneric("equals", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
+		tp, _ := gens[0].calc(s)
+		return equalFunc(m, tp, s)
+	})
+
+	// kindof and tointerface are used by the fmt runtime
+	s.globalScope.addGeneric("kindof", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
+		tp, _ := gens[0].calc(s)
+		return kindFunc(m, tp, s)
+	})
+
+	s.globalScope.addGeneric("tointerface", func(m *ir.Module, s *Scope, gens ...TypeNode) value.Value {
+		tp, _ := gens[0].calc(s)
+		it, _ := gens[1].calc(s)
+		return toInterfaceFunc(m, tp, it, s)
+	})
+
 }
@@ -0,0 +1,36 @@
+package ast
+
+import (
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/value"
+)
+
+// strMethod calls the method name of string l with r as the param
+func strMethod(m *ir.Module, f *ir.Func, s *Scope, name string, l, r value.Value) value.Value {
+	recv := stackAlloc(m, s, l.Type())
+	store(l, recv, s)
+	return callMethod(m, f, s, recv, &CallFuncNode{
+		FnNode: &VarBlockNode{Token: name},
+		Params: []Node{&fakeNode{v: r}},
+	})
+}
+
+// strConcat calculates l + r of strings by Append
+func strConcat(m *ir.Module, f *ir.Func, s *Scope, l, r value.Value) (value.Value, bool) {
+	if !isStrType(l.Type()) || !isStrType(r.Type()) {
+		return nil, false
+	}
+	return strMethod(m, f, s, "Append", l, r), true
+}
+
+// strCompare compares strings l and r by Compare, which returns an int
+// having the same order as them
+func strCompare(m *ir.Module, f *ir.Func, s *Scope, op int, l, r value.Value) (value.Value, bool) {
+	if !isStrType(l.Type()) || !isStrType(r.Type()) {
+		return nil, false
+	}
+	c := loadIfVar(strMethod(m, f, s, "Compare", l, r), s)
+	return s.block.NewICmp(comparedic[op].IntE, c, constant.NewInt(lexer.DefaultIntType(), 0)), true
+}
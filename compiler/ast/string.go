@@ -1,40 +1,57 @@
 package ast
 
 import (
+	"fmt"
+
 	"github.com/Chronostasys/calc/compiler/lexer"
 	"github.com/llir/llvm/ir"
 	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/enum"
 	"github.com/llir/llvm/ir/types"
 	"github.com/llir/llvm/ir/value"
 )
 
+// strLits maps the string literals to their globals in each module, so
+// that identical literals of all packages share one global
+var strLits = map[*ir.Module]map[string]*ir.Global{}
+
 type StringNode struct {
-	Str    string
-	onheap bool
+	Str string
 }
 
 func (b *StringNode) tp() TypeNode {
 	return &calcedTypeNode{getstrtp()}
 }
 
+// setAlloc does nothing, literals are globals and never allocated
 func (n *StringNode) setAlloc(onheap bool) {
-	n.onheap = onheap
 }
 func (n *StringNode) travel(f func(Node) bool) {
 	f(n)
 }
 
 func (n *StringNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	return constStr(m, n.Str)
+}
 
-	ch := constant.NewCharArrayFromString(n.Str)
-	var alloca = gcmalloc(m, s, &calcedTypeNode{ch.Type()})
-	// if n.onheap {
-	// 	alloca = gcmalloc(m, s, &calcedTypeNode{ch.Type()})
-	// } else {
-	// 	alloca = stackAlloc(m, s, ch.Type())
-	// }
-	s.block.NewStore(ch, alloca)
-	bs := s.block.NewBitCast(alloca, types.I8Ptr)
-	va, _ := ScopeMap["github.com/Chronostasys/calc/runtime/strings"].searchVar("NewStr")
-	return s.block.NewCall(va.v, bs, constant.NewInt(lexer.DefaultIntType(), int64(ch.Typ.Len)))
+// constStr returns a constant string pointing at the global of str, the
+// global is private and emitted once. A NUL follows the bytes of str, so
+// that literals can be passed to C functions taking *byte
+func constStr(m *ir.Module, str string) constant.Constant {
+	lits := strLits[m]
+	if lits == nil {
+		lits = map[string]*ir.Global{}
+		strLits[m] = lits
+	}
+	g, ok := lits[str]
+	if !ok {
+		g = m.NewGlobalDef(fmt.Sprintf("..str%d", len(lits)), constant.NewCharArrayFromString(str+"\x00"))
+		g.Immutable = true
+		g.Linkage = enum.LinkagePrivate
+		g.UnnamedAddr = enum.UnnamedAddrUnnamedAddr
+		lits[str] = g
+	}
+	head := constant.NewGetElementPtr(g.ContentType, g, zero, zero)
+	return constant.NewStruct(getstrtp().(*types.StructType), head,
+		constant.NewInt(lexer.DefaultIntType(), int64(len(str))))
 }
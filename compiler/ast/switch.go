@@ -0,0 +1,145 @@
+package ast
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Chronostasys/calc/compiler/lexer"
+	"github.com/llir/llvm/ir"
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+type SwitchNode struct {
+	Tag     ExpNode
+	Cases   []*CaseNode
+	Default Node
+	File    string
+	Line    int
+}
+
+type CaseNode struct {
+	Vals       []ExpNode
+	Statements Node
+	Line       int
+}
+
+func (n *SwitchNode) travel(f func(Node) bool) {
+	f(n)
+	n.Tag.travel(f)
+	for _, c := range n.Cases {
+		for _, v := range c.Vals {
+			v.travel(f)
+		}
+		c.Statements.travel(f)
+	}
+	if n.Default != nil {
+		n.Default.travel(f)
+	}
+}
+
+// bodies returns the statements of all cases, default comes last
+func (n *SwitchNode) bodies() []Node {
+	bodies := []Node{}
+	for _, c := range n.Cases {
+		bodies = append(bodies, c.Statements)
+	}
+	if n.Default != nil {
+		bodies = append(bodies, n.Default)
+	}
+	return bodies
+}
+
+func (n *SwitchNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
+	tag := loadIfVar(n.Tag.calc(m, f, s), s)
+	blockID++
+	end := f.NewBlock(strconv.Itoa(blockID))
+	def := end
+	if n.Default != nil {
+		blockID++
+		def = f.NewBlock(strconv.Itoa(blockID))
+	}
+	blocks := []*ir.Block{}
+	for range n.Cases {
+		blockID++
+		blocks = append(blocks, f.NewBlock(strconv.Itoa(blockID)))
+	}
+
+	// int tag with constant cases compiles to a jump table,
+	// others are compared one by one
+	itp, isInt := tag.Type().(*types.IntType)
+	cases := []*ir.Case{}
+	covered := map[int64]bool{}
+	for i, c := range n.Cases {
+		for _, v := range c.Vals {
+			cv, err := constEval(v, s, -1)
+			ci, ok := cv.(*constant.Int)
+			if !isInt || err != nil || !ok {
+				isInt = false
+				break
+			}
+			if covered[ci.X.Int64()] {
+				panic(fmt.Errorf("duplicate case %v in switch (%s:%d)", ci.X, n.File, c.Line))
+			}
+			covered[ci.X.Int64()] = true
+			cases = append(cases, ir.NewCase(&constant.Int{Typ: itp, X: ci.X}, blocks[i]))
+		}
+	}
+	if isInt {
+		s.block.NewSwitch(tag, def, cases...)
+		n.checkExhaustive(itp, covered)
+	} else {
+		lits := map[string]bool{}
+		for i, c := range n.Cases {
+			for _, v := range c.Vals {
+				if str, ok := v.(*StringNode); ok {
+					if lits[str.Str] {
+						panic(fmt.Errorf("duplicate case %q in switch (%s:%d)", str.Str, n.File, c.Line))
+					}
+					lits[str.Str] = true
+				}
+				cmp := &CompareNode{Op: lexer.TYPE_EQ, Left: &fakeNode{v: tag}, Right: v}
+				blockID++
+				next := f.NewBlock(strconv.Itoa(blockID))
+				s.block.NewCondBr(loadIfVar(cmp.calc(m, f, s), s), blocks[i], next)
+				s.block = next
+			}
+		}
+		s.block.NewBr(def)
+	}
+	s.block = end
+	blocks = append(blocks, def)
+	for i, st := range n.bodies() {
+		child := s.addChildScope(blocks[i])
+		child.breakBlock = end
+		st.calc(m, f, child)
+		if child.block.Term == nil {
+			child.block.NewBr(end)
+		}
+	}
+	if s.parent.block != nil {
+		end.NewBr(s.parent.block)
+	}
+	return zero
+}
+
+// checkExhaustive warns about enum members not handled by a switch without
+// default
+func (n *SwitchNode) checkExhaustive(tp *types.IntType, covered map[int64]bool) {
+	members, ok := enumTypes[tp.TypeName]
+	if !ok || n.Default != nil {
+		return
+	}
+	missing := []string{}
+	for i, v := range members {
+		if !covered[int64(i)] {
+			missing = append(missing, v)
+		}
+	}
+	if len(missing) > 0 {
+		warn("switch on %s is not exhaustive, missing %s (%s:%d)",
+			tp.TypeName, strings.Join(missing, ", "), n.File, n.Line)
+	}
+}
@@ -75,6 +75,7 @@ func (v *FuncTypeNode) calc(s *Scope) (types.Type, error) {
 	}
 	var fn types.Type
 	fn = types.NewFunc(ret, args...)
+	markVariadic(fn.(*types.FuncType), v.Args)
 	v.ptrlevel++
 	for i := 0; i < v.ptrlevel; i++ {
 		fn = types.NewPointer(fn)
@@ -117,11 +118,12 @@ type ArrayTypeNode struct {
 	Len      int
 	ElmType  TypeNode
 	PtrLevel int
+	LenExp   ExpNode // constant expression length, like [N*2]int
 }
 
 func (n *ArrayTypeNode) Clone() TypeNode {
 	return &ArrayTypeNode{
-		n.Len, n.ElmType, n.PtrLevel,
+		n.Len, n.ElmType, n.PtrLevel, n.LenExp,
 	}
 }
 
@@ -161,7 +163,20 @@ func (v *ArrayTypeNode) calc(s *Scope) (types.Type, error) {
 		return nil, err
 	}
 	var tp types.Type
-	if v.Len > 0 {
+	if v.LenExp != nil {
+		c, err := constEval(v.LenExp, s, -1)
+		if err != nil {
+			return nil, fmt.Errorf("array length: %v", err)
+		}
+		l, ok := c.(*constant.Int)
+		if !ok || l.X.Sign() <= 0 {
+			return nil, fmt.Errorf("invalid array length %v", c)
+		}
+		// the type may be calculated in other scopes later (e.g. in
+		// generic functions of other packages), so keep the result
+		v.Len, v.LenExp = int(l.X.Int64()), nil
+		tp = types.NewArray(l.X.Uint64(), elm)
+	} else if v.Len > 0 {
 		tp = types.NewArray(uint64(v.Len), elm)
 	} else {
 		gnf := ScopeMap[SLICE].getGenericStruct("Slice")
@@ -195,10 +210,11 @@ func (v *BasicTypeNode) calc(sc *Scope) (types.Type, error) {
 						gs := oris.paramGenerics[oris.currParam]
 						if gs != nil {
 							for i, v := range v.Generics {
-								if len(v.(*BasicTypeNode).CustomTp) == 0 {
+								bt, ok := v.(*BasicTypeNode)
+								if !ok || len(bt.CustomTp) == 0 {
 									break
 								}
-								k := v.(*BasicTypeNode).CustomTp[0]
+								k := bt.CustomTp[0]
 								ss := strings.Split(k, ".")
 								k = ss[len(ss)-1]
 								if i < len(gs) {
@@ -274,6 +290,11 @@ func (n *ArrayInitNode) tp() TypeNode {
 
 func (n *ArrayInitNode) setAlloc(onheap bool) {
 	n.allocOnHeap = onheap
+	if onheap {
+		for _, v := range n.Vals {
+			escapeTakenPtr(v)
+		}
+	}
 }
 
 func (n *ArrayInitNode) travel(f func(Node) bool) {
@@ -286,9 +307,12 @@ func (n *ArrayInitNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	if err != nil {
 		panic(err)
 	}
+	if _, ok := atype.(*types.ArrayType); !ok {
+		return n.sliceInit(m, f, s, atype)
+	}
 	var alloca value.Value
 	if n.allocOnHeap {
-		alloca = gcmalloc(m, s, n.Type)
+		alloca = heapAlloc(m, s, n.Type)
 	} else {
 		alloca = stackAlloc(m, s, atype)
 	}
@@ -308,6 +332,25 @@ func (n *ArrayInitNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 
 func (n *StructInitNode) setAlloc(onheap bool) {
 	n.allocOnHeap = onheap
+	if onheap {
+		for _, v := range n.Fields {
+			escapeTakenPtr(v)
+		}
+	}
+}
+
+// escapeTakenPtr allocates literals like &T{} on heap, it is called on the
+// elements of literals on heap, whose pointers escape with them
+func escapeTakenPtr(n Node) {
+	tp, ok := n.(*TakePtrNode)
+	if !ok {
+		return
+	}
+	if a, ok := tp.Node.(alloca); ok {
+		if _, ok := a.(*VarBlockNode); !ok {
+			a.setAlloc(true)
+		}
+	}
 }
 func (n *StructInitNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	t, err := n.TP.calc(s)
@@ -335,9 +378,11 @@ func (n *StructInitNode) calc(m *ir.Module, f *ir.Func, s *Scope) value.Value {
 	}
 	var alloca value.Value
 	if n.allocOnHeap {
-		alloca = gcmalloc(m, s, n.TP)
+		alloca = heapAlloc(m, s, n.TP)
 	} else {
+		// fields not given are zero values, like the memory of gcmalloc
 		alloca = stackAlloc(m, s, tp.structType)
+		s.block.NewStore(constant.NewZeroInitializer(tp.structType), alloca)
 	}
 
 	var va value.Value = alloca
@@ -364,8 +409,9 @@ type StructDefNode struct {
 }
 
 type Field struct {
-	Name string
-	TP   TypeNode
+	Name     string
+	TP       TypeNode
+	Embedded bool
 }
 
 func (n *StructDefNode) Clone() TypeNode {
@@ -384,16 +430,16 @@ func (v *StructDefNode) SetPtrLevel(i int) {
 func (v *StructDefNode) calc(s *Scope) (types.Type, error) {
 	fields := []types.Type{}
 	fieldsIdx := map[string]*field{}
-	for i := range v.Orderedfields {
-		k, v := v.Orderedfields[i].Name, v.Orderedfields[i].TP
-		tp, err := v.calc(s)
+	for i, f := range v.Orderedfields {
+		tp, err := f.TP.calc(s)
 		if err != nil {
 			return nil, err
 		}
 		fields = append(fields, tp)
-		fieldsIdx[k] = &field{
-			idx:   i,
-			ftype: fields[i],
+		fieldsIdx[f.Name] = &field{
+			idx:      i,
+			ftype:    fields[i],
+			embedded: f.Embedded,
 		}
 	}
 	var tp types.Type
@@ -433,11 +479,12 @@ type InterfaceDefNode struct {
 	ptrlevel   int
 	Funcs      map[string]*FuncNode
 	OrderedIDS []string
+	Embeds     []TypeNode // 嵌入的接口
 }
 
 func (n *InterfaceDefNode) Clone() TypeNode {
 	return &InterfaceDefNode{
-		n.ptrlevel, n.Funcs, n.OrderedIDS,
+		n.ptrlevel, n.Funcs, n.OrderedIDS, n.Embeds,
 	}
 }
 
@@ -451,16 +498,49 @@ func (v *InterfaceDefNode) SetPtrLevel(i int) {
 func (v *InterfaceDefNode) calc(s *Scope) (types.Type, error) {
 	var tp types.Type
 	tps := []types.Type{lexer.DefaultIntType()}
-	i := 1
+	funcs := map[string]*FuncNode{}
+	ids := []string{}
+	add := func(k string, fn *FuncNode) error {
+		if old, ok := funcs[k]; ok {
+			if !sameMethod(old, fn, s) {
+				return fmt.Errorf("duplicate method %s", k)
+			}
+			return nil
+		}
+		funcs[k] = fn
+		ids = append(ids, k)
+		return nil
+	}
+	// the method set is flattened, methods of embedded interfaces come first
+	for _, e := range v.Embeds {
+		et, err := e.calc(s)
+		if err != nil {
+			return nil, err
+		}
+		in, ok := et.(*interf)
+		if !ok {
+			return nil, fmt.Errorf("cannot embed non-interface type %v in interface", et)
+		}
+		for _, k := range in.methods() {
+			fn := *in.interfaceFuncs[k] // indexes are different in each interface
+			if err := add(k, &fn); err != nil {
+				return nil, err
+			}
+		}
+	}
 	for _, k := range v.OrderedIDS {
+		if err := add(k, v.Funcs[k]); err != nil {
+			return nil, err
+		}
+	}
+	for i, k := range ids {
 		tps = append(tps, lexer.DefaultIntType())
-		v.Funcs[k].i = i
-		i++
+		funcs[k].i = i + 1
 	}
 	interfaceTp := types.NewStruct(tps...)
 	tp = &interf{
 		Type:           interfaceTp,
-		interfaceFuncs: v.Funcs,
+		interfaceFuncs: funcs,
 	}
 
 	for i := 0; i < v.ptrlevel; i++ {
@@ -547,8 +627,22 @@ func NewTypeDef(id string, tp TypeNode, generics []string, m *ir.Module, s *Scop
 
 			if tt, ok := t.(*types.StructType); ok {
 				tmpss.Fields = tt.Fields
+				if tt.TypeName != "" {
+					// type B A, B has the fields of A
+					name := helper.SplitLast(tt.TypeName, ".")
+					if sc := ScopeMap[name[0]]; sc != nil && len(name) > 1 {
+						if def := sc.getStruct(name[1]); def != nil {
+							for k, v := range def.fieldsIdx {
+								fidx[k] = v
+							}
+						}
+					}
+				}
+			}
+			td.structType = m.NewTypeDef(s.getFullName(n.id), copyBasicType(t))
+			if e, ok := tp.(*EnumDefNode); ok {
+				e.define(m, s, n.id, td.structType.(*types.IntType))
 			}
-			td.structType = m.NewTypeDef(s.getFullName(n.id), t)
 
 			// s.globalScope.addStruct(n.id, &typedef{
 			// 	structType: m.NewTypeDef(s.getFullName(n.id), t),
@@ -574,7 +668,7 @@ func NewTypeDef(id string, tp TypeNode, generics []string, m *ir.Module, s *Scop
 				}
 				genericMap[generics[i]] = tp
 				generictypes = append(generictypes, tp)
-				sig += tp.String() + ","
+				sig += typeSig(tp) + ","
 			}
 		}
 		sig += ">"
@@ -0,0 +1,85 @@
+package ast
+
+import (
+	"fmt"
+
+	"github.com/llir/llvm/ir/constant"
+	"github.com/llir/llvm/ir/types"
+	"github.com/llir/llvm/ir/value"
+)
+
+// variadicFuncs holds the signatures of functions whose last parameter is
+// variadic (args ...T). The parameter is a slice in llvm, so like
+// unsignedTypes, it is decided by the identity of the signature.
+var variadicFuncs = map[*types.FuncType]bool{}
+
+func markVariadic(sig *types.FuncType, ps *ParamsNode) {
+	if ps.Variadic {
+		variadicFuncs[sig] = true
+	}
+	if ps.CVariadic {
+		sig.Variadic = true
+	}
+}
+
+// variadicArgs checks the arguments of a call to fntp, and collects the
+// trailing ones into a slice if fntp is variadic. off is the number of
+// params passed before pvs (the receiver of methods).
+func variadicArgs(fntp *types.FuncType, pvs []value.Value, off int, spread bool, s *Scope) []value.Value {
+	fixed := len(fntp.Params) - off
+	if !variadicFuncs[fntp] {
+		if spread {
+			panic(fmt.Errorf("cannot use ... in call to non-variadic function"))
+		}
+		if len(pvs) > fixed && fntp.Variadic {
+			// c variadic args are promoted like in c, floats to double
+			// and ints narrower than int32 to int32
+			for i := fixed; i < len(pvs); i++ {
+				switch tp := pvs[i].Type().(type) {
+				case *types.FloatType:
+					if tp.Kind == types.FloatKindFloat || tp.Kind == types.FloatKindHalf {
+						pvs[i] = s.block.NewFPExt(pvs[i], types.Double)
+					}
+				case *types.IntType:
+					if tp.BitSize == 1 || isUnsigned(tp) && tp.BitSize < 32 {
+						pvs[i] = s.block.NewZExt(pvs[i], types.I32)
+					} else if tp.BitSize < 32 {
+						pvs[i] = s.block.NewSExt(pvs[i], types.I32)
+					}
+				}
+			}
+			return pvs
+		}
+		if len(pvs) != fixed {
+			panic(fmt.Errorf("expect %d arguments, got %d", fixed, len(pvs)))
+		}
+		return pvs
+	}
+	fixed--
+	if spread {
+		if len(pvs) != fixed+1 {
+			panic(fmt.Errorf("cannot use ... with %d arguments, expect %d", len(pvs), fixed+1))
+		}
+		return pvs
+	}
+	if len(pvs) < fixed {
+		panic(fmt.Errorf("expect at least %d arguments, got %d", fixed, len(pvs)))
+	}
+	slicetp := fntp.Params[len(fntp.Params)-1].(*types.PointerType).ElemType.(*types.StructType)
+	elm := slicetp.Fields[0].(*types.PointerType).ElemType
+	args := pvs[fixed:]
+	arrtp := types.NewArray(uint64(len(args)), elm)
+	// each call gets its own array, the callee may keep the slice
+	arr := heapAlloc(s.m, s, &calcedTypeNode{arrtp})
+	for i, v := range args {
+		v1, err := implicitCast(v, elm, s)
+		if err != nil {
+			panic(err)
+		}
+		store(v1, s.block.NewGetElementPtr(arrtp, arr, zero, constant.NewInt(types.I32, int64(i))), s)
+	}
+	head := s.block.NewGetElementPtr(arrtp, arr, zero, zero)
+	slicef := ScopeMap[SLICE].getGenericFunc("FromArr")(s.m, &calcedTypeNode{elm})
+	sl := s.block.NewCall(slicef, head, constant.NewInt(types.I32, int64(len(args))))
+	return append(pvs[:fixed:fixed], sl)
+}
@@ -73,6 +73,23 @@ const (
 	TYPE_RES_YIELD     // "yield"
 	TYPE_RES_ASYNC     // "async"
 	TYPE_RES_AWAIT     // "await"
+	TYPE_RES_CONST     // "const"
+	TYPE_RES_ENUM      // "enum"
+	TYPE_RES_SWITCH    // "switch"
+	TYPE_RES_CASE      // "case"
+	TYPE_RES_DEFAULT   // "default"
+	TYPE_RES_INT8      // "int8"
+	TYPE_RES_INT16     // "int16"
+	TYPE_RES_UINT      // "uint"
+	TYPE_RES_UINT8     // "uint8"
+	TYPE_RES_UINT16    // "uint16"
+	TYPE_RES_UINT32    // "uint32"
+	TYPE_RES_UINT64    // "uint64"
+	TYPE_RES_UINTPTR   // "uintptr"
+	TYPE_ELLIPSIS      // "..." 可变参数
+	TYPE_RES_MAP       // "map"
+	TYPE_RES_RANGE     // "range"
+	TYPE_RES_RUNE      // "rune"
 )
 
 var (
@@ -108,6 +125,22 @@ var (
 		"yield":     TYPE_RES_YIELD,
 		"async":     TYPE_RES_ASYNC,
 		"await":     TYPE_RES_AWAIT,
+		"const":     TYPE_RES_CONST,
+		"enum":      TYPE_RES_ENUM,
+		"switch":    TYPE_RES_SWITCH,
+		"case":      TYPE_RES_CASE,
+		"default":   TYPE_RES_DEFAULT,
+		"int8":      TYPE_RES_INT8,
+		"int16":     TYPE_RES_INT16,
+		"uint":      TYPE_RES_UINT,
+		"uint8":     TYPE_RES_UINT8,
+		"uint16":    TYPE_RES_UINT16,
+		"uint32":    TYPE_RES_UINT32,
+		"uint64":    TYPE_RES_UINT64,
+		"uintptr":   TYPE_RES_UINTPTR,
+		"map":       TYPE_RES_MAP,
+		"range":     TYPE_RES_RANGE,
+		"rune":      TYPE_RES_RUNE,
 	}
 	reservedTypes = map[string]int{
 		"int":     TYPE_RES_INT,
@@ -120,6 +153,15 @@ var (
 		"float64": TYPE_RES_FLOAT64,
 		"byte":    TYPE_RES_BYTE,
 		"string":  TYPE_RES_STR,
+		"int8":    TYPE_RES_INT8,
+		"int16":   TYPE_RES_INT16,
+		"uint":    TYPE_RES_UINT,
+		"uint8":   TYPE_RES_UINT8,
+		"uint16":  TYPE_RES_UINT16,
+		"uint32":  TYPE_RES_UINT32,
+		"uint64":  TYPE_RES_UINT64,
+		"uintptr": TYPE_RES_UINTPTR,
+		"rune":    TYPE_RES_RUNE,
 	}
 	ErrEOS  = fmt.Errorf("eos error")
 	ErrTYPE = fmt.Errorf("the next token doesn't match the expected type")
@@ -186,6 +228,10 @@ func isNum(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
+func isHex(ch rune) bool {
+	return isNum(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
 type Checkpoint struct {
 	pos int
 }
@@ -342,11 +388,13 @@ START:
 		i := []rune{ch}
 		t := TYPE_INT
 		next, _ := l.Peek()
+		hex := false
 		if ch == '0' && (next == 'b' ||
 			next == 'o' ||
 			next == 'x') {
 			l.getCh()
 			i = append(i, next)
+			hex = next == 'x'
 		}
 		for {
 			c, end := l.getCh()
@@ -358,7 +406,7 @@ START:
 				t = TYPE_FLOAT
 				continue
 			}
-			if !isNum(c) {
+			if !isNum(c) && !(hex && isHex(c)) {
 				l.pos--
 				break
 			}
@@ -421,7 +469,7 @@ START:
 			l.getCh()
 			return TYPE_OR, "||", end
 		}
-		return TYPE_OR, "|", end
+		return TYPE_BIT_OR, "|", end
 	case '>':
 		ne, _ := l.Peek()
 		if ne == '=' {
@@ -461,6 +509,10 @@ START:
 	case ']':
 		return TYPE_RSB, "]", end
 	case '.':
+		if l.pos+1 < len(l.runes) && l.runes[l.pos] == '.' && l.runes[l.pos+1] == '.' {
+			l.pos += 2
+			return TYPE_ELLIPSIS, "...", end
+		}
 		return TYPE_DOT, ".", end
 	case '%':
 		return TYPE_PS, "%", end
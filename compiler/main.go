@@ -7,14 +7,26 @@ import (
 	"os"
 	"time"
 
+	"github.com/Chronostasys/calc/compiler/ast"
 	"github.com/Chronostasys/calc/compiler/parser"
 )
 
 func main() {
-	var indir, outf string
+	var indir, outf, overflow string
+	var noBounds bool
 	flag.StringVar(&indir, "d", ".", "source repo dir")
 	flag.StringVar(&outf, "o", "out.ll", "llvm ir file")
+	flag.BoolVar(&noBounds, "B", false, "disable bounds checking")
+	flag.StringVar(&overflow, "overflow", "wrap", "signed integer overflow mode, wrap or trap")
 	flag.Parse()
+	ast.BoundsCheck = !noBounds
+	switch overflow {
+	case "wrap":
+	case "trap":
+		ast.OverflowTrap = true
+	default:
+		log.Fatalf("invalid overflow mode %q, must be wrap or trap", overflow)
+	}
 	since := time.Now()
 	defer func() {
 		err := recover()
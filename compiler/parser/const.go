@@ -0,0 +1,70 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/ast"
+	"github.com/Chronostasys/calc/compiler/lexer"
+)
+
+func (p *Parser) constDef() (n ast.Node, err error) {
+	_, err = p.lexer.ScanType(lexer.TYPE_RES_CONST)
+	if err != nil {
+		return nil, err
+	}
+	cn := &ast.ConstNode{}
+	_, err = p.lexer.ScanType(lexer.TYPE_LP)
+	if err != nil {
+		spec, err := p.constSpec(nil, 0)
+		if err != nil {
+			return nil, err
+		}
+		cn.Specs = append(cn.Specs, spec)
+		return cn, nil
+	}
+	var prev *ast.ConstSpec
+	for i := 0; ; {
+		_, err = p.lexer.ScanType(lexer.TYPE_RP)
+		if err == nil {
+			break
+		}
+		_, err = p.lexer.ScanType(lexer.TYPE_NL)
+		if err == nil {
+			continue
+		}
+		prev, err = p.constSpec(prev, i)
+		if err != nil {
+			return nil, err
+		}
+		cn.Specs = append(cn.Specs, prev)
+		i++
+	}
+	return cn, nil
+}
+
+// constSpec parses `ID [Type] [= exp]`. The value can only be omitted inside
+// a const block, then the previous spec is repeated.
+func (p *Parser) constSpec(prev *ast.ConstSpec, iota int) (n *ast.ConstSpec, err error) {
+	id, err := p.lexer.ScanType(lexer.TYPE_VAR)
+	if err != nil {
+		return nil, err
+	}
+	n = &ast.ConstSpec{ID: id, Iota: iota}
+	code, _, _ := p.lexer.PeekToken()
+	if code != lexer.TYPE_ASSIGN && code != lexer.TYPE_NL && code != lexer.TYPE_RP {
+		n.TP, err = p.allTypes()
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_ASSIGN)
+	if err != nil {
+		if prev == nil || n.TP != nil {
+			return nil, fmt.Errorf("missing value of const %s", id)
+		}
+		n.TP, n.Val = prev.TP, prev.Val
+		return n, nil
+	}
+	n.Val = p.allexp()
+	return n, nil
+}
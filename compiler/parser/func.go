@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 
 	"github.com/Chronostasys/calc/compiler/ast"
@@ -21,11 +22,16 @@ func (p *Parser) funcParam() *ast.ParamNode {
 	if err != nil {
 		panic(err)
 	}
+	_, err = p.lexer.ScanType(lexer.TYPE_ELLIPSIS)
+	variadic := err == nil
 	tp, err := p.allTypes()
 	if err != nil {
 		panic(err)
 	}
-	return &ast.ParamNode{ID: t, TP: tp}
+	if variadic { // 可变参数以slice的形式传入
+		tp = &ast.ArrayTypeNode{Len: -1, ElmType: tp}
+	}
+	return &ast.ParamNode{ID: t, TP: tp, Variadic: variadic}
 }
 
 func (p *Parser) funcParams() *ast.ParamsNode {
@@ -48,6 +54,7 @@ func (p *Parser) funcParams() *ast.ParamsNode {
 		pn.Params = append(pn.Params, n)
 		pn.Ext = true
 	}
+	pn.Variadic = pn.Params[0].Variadic
 	for {
 		_, err = p.lexer.ScanType(lexer.TYPE_RP)
 		if err == nil {
@@ -60,7 +67,20 @@ func (p *Parser) funcParams() *ast.ParamsNode {
 		if err != nil {
 			panic(err)
 		}
+		if pn.Variadic {
+			panic(fmt.Errorf("variadic parameter must be the last parameter"))
+		}
+		_, err = p.lexer.ScanType(lexer.TYPE_ELLIPSIS)
+		if err == nil { // c的可变参数，只用于外部函数声明
+			pn.CVariadic = true
+			_, err = p.lexer.ScanType(lexer.TYPE_RP)
+			if err != nil {
+				panic(err)
+			}
+			return pn
+		}
 		pn.Params = append(pn.Params, p.funcParam())
+		pn.Variadic = pn.Params[len(pn.Params)-1].Variadic
 	}
 }
 
@@ -118,6 +138,15 @@ func (p *Parser) callFunc() ast.ExpNode {
 	}
 	fn.Params = append(fn.Params, p.allexp())
 	for {
+		_, err = p.lexer.ScanType(lexer.TYPE_ELLIPSIS)
+		if err == nil { // f(xs...)
+			fn.Spread = true
+			_, err = p.lexer.ScanType(lexer.TYPE_RP)
+			if err != nil {
+				panic(err)
+			}
+			goto END
+		}
 		_, err = p.lexer.ScanType(lexer.TYPE_RP)
 		if err == nil {
 			goto END
@@ -0,0 +1,214 @@
+package parser
+
+import (
+	"fmt"
+
+	"github.com/Chronostasys/calc/compiler/ast"
+	"github.com/Chronostasys/calc/compiler/lexer"
+)
+
+// rangeIter is the hidden iterator variable of range loops
+const rangeIter = "_rangeiter"
+
+func (p *Parser) mapType() (n *ast.MapTypeNode, err error) {
+	ch := p.lexer.SetCheckpoint()
+	defer func() {
+		if err != nil {
+			p.lexer.GobackTo(ch)
+		}
+	}()
+	_, err = p.lexer.ScanType(lexer.TYPE_RES_MAP)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_LSB)
+	if err != nil {
+		return nil, err
+	}
+	n = &ast.MapTypeNode{}
+	n.Key, err = p.allTypes()
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_RSB)
+	if err != nil {
+		return nil, err
+	}
+	n.Val, err = p.allTypes()
+	if err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+func (p *Parser) mapInit() (n ast.ExpNode, err error) {
+	tp, err := p.mapType()
+	if err != nil {
+		return nil, err
+	}
+	mn := &ast.MapInitNode{Type: tp}
+	_, err = p.lexer.ScanType(lexer.TYPE_LB)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		_, err = p.lexer.ScanType(lexer.TYPE_RB)
+		if err == nil {
+			break
+		}
+		_, err = p.lexer.ScanType(lexer.TYPE_NL)
+		if err == nil {
+			continue
+		}
+		mn.Keys = append(mn.Keys, p.allexp())
+		_, err = p.lexer.ScanType(lexer.TYPE_COLON)
+		if err != nil {
+			return nil, err
+		}
+		mn.Vals = append(mn.Vals, p.allexp())
+		_, err = p.lexer.ScanType(lexer.TYPE_COMMA)
+		if err != nil {
+			p.lexer.ScanType(lexer.TYPE_NL)
+			_, err = p.lexer.ScanType(lexer.TYPE_RB)
+			if err != nil {
+				return nil, err
+			}
+			break
+		}
+	}
+	return mn, nil
+}
+
+// commaOk parses `v, ok := m[k]` and `v, ok = m[k]`. It is lowered to
+// `ok := false` and `v := m.Lookup(k, &ok)`, so any type with a Lookup
+// method can be used.
+func (p *Parser) commaOk() (n ast.Node, err error) {
+	val, err := p.lexer.ScanType(lexer.TYPE_VAR)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_COMMA)
+	if err != nil {
+		return nil, err
+	}
+	ok, err := p.lexer.ScanType(lexer.TYPE_VAR)
+	if err != nil {
+		return nil, err
+	}
+	def := false
+	_, err = p.lexer.ScanType(lexer.TYPE_DEAS)
+	if err == nil {
+		def = true
+	} else {
+		_, err = p.lexer.ScanType(lexer.TYPE_ASSIGN)
+		if err != nil {
+			return nil, err
+		}
+	}
+	head, err := p.varChain()
+	if err != nil {
+		return nil, err
+	}
+	tail := head.(*ast.VarBlockNode)
+	for tail.Next != nil {
+		tail = tail.Next
+	}
+	if len(tail.Idxs) == 0 {
+		return nil, fmt.Errorf("expect index expression")
+	}
+	key := tail.Idxs[len(tail.Idxs)-1]
+	tail.Idxs = tail.Idxs[:len(tail.Idxs)-1]
+	tail.Next = &ast.VarBlockNode{Token: "Lookup"}
+	call := &ast.CallFuncNode{
+		FnNode: head,
+		Params: []ast.Node{key, &ast.TakePtrNode{Node: &ast.VarBlockNode{Token: ok}}},
+	}
+	sl := &ast.SLNode{}
+	if def {
+		sl.Children = append(sl.Children, &ast.DefAndAssignNode{ValNode: &ast.BoolConstNode{}, ID: ok})
+	}
+	switch {
+	case val == "_":
+		sl.Children = append(sl.Children, call)
+	case def:
+		sl.Children = append(sl.Children, &ast.DefAndAssignNode{ValNode: call, ID: val})
+	default:
+		sl.Children = append(sl.Children, &ast.BinNode{
+			Left:  &ast.TakeValNode{Node: &ast.VarBlockNode{Token: val}},
+			Op:    lexer.TYPE_ASSIGN,
+			Right: call,
+		})
+	}
+	p.empty()
+	return sl, nil
+}
+
+// rangeLoop parses `for k, v := range x {}`. It is lowered to
+//
+//	for it := x.Iter(); it.Next(); {
+//		k := it.Key()
+//		v := it.Value()
+//		...
+//	}
+//
+// so any type with an Iter method can be ranged over.
+func (p *Parser) rangeLoop() (n ast.Node, err error) {
+	_, err = p.lexer.ScanType(lexer.TYPE_RES_FOR)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{}
+	for {
+		id, err := p.lexer.ScanType(lexer.TYPE_VAR)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+		_, err = p.lexer.ScanType(lexer.TYPE_COMMA)
+		if err != nil || len(ids) == 2 {
+			break
+		}
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_DEAS)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_RES_RANGE)
+	if err != nil {
+		return nil, err
+	}
+	x := p.allexp()
+	st, err := p.statementBlock()
+	if err != nil {
+		return nil, err
+	}
+	iter := func(method string) ast.Node {
+		return &ast.CallFuncNode{FnNode: &ast.VarBlockNode{
+			Token: rangeIter,
+			Next:  &ast.VarBlockNode{Token: method},
+		}}
+	}
+	body := &ast.SLNode{}
+	for i, id := range ids {
+		if id == "_" {
+			continue
+		}
+		method := "Key"
+		if i == 1 {
+			method = "Value"
+		}
+		body.Children = append(body.Children, &ast.DefAndAssignNode{ValNode: iter(method), ID: id})
+	}
+	body.Children = append(body.Children, st.(*ast.SLNode).Children...)
+	return &ast.ForNode{
+		DefineAssign: &ast.DefAndAssignNode{
+			ValNode: &ast.CallFuncNode{
+				FnNode: &ast.VarBlockNode{Token: "range"},
+				Params: []ast.Node{x},
+			},
+			ID: rangeIter,
+		},
+		Bool:       iter("Next"),
+		Statements: body,
+	}, nil
+}
@@ -57,6 +57,7 @@ func (p *Parser) number() (n ast.ExpNode) {
 }
 
 func (p *Parser) factor() ast.ExpNode {
+	pos := p.lexer.GetPos()
 	a := p.symbol()
 	ch := p.lexer.SetCheckpoint()
 	code, _, eos := p.lexer.Scan()
@@ -64,9 +65,12 @@ func (p *Parser) factor() ast.ExpNode {
 		code == lexer.TYPE_MUL || code == lexer.TYPE_PS {
 		b := p.symbol()
 		a = &ast.BinNode{
-			Op:    code,
-			Left:  a,
-			Right: b,
+			Op:      code,
+			Left:    a,
+			Right:   b,
+			Pos:     pos,
+			Lexer:   p.lexer,
+			SrcFile: p.path,
 		}
 		ch = p.lexer.SetCheckpoint()
 		code, _, eos = p.lexer.Scan()
@@ -78,15 +82,19 @@ func (p *Parser) factor() ast.ExpNode {
 }
 
 func (p *Parser) exp() ast.ExpNode {
+	pos := p.lexer.GetPos()
 	a := p.addedFactor()
 	ch := p.lexer.SetCheckpoint()
 	code, _, eos := p.lexer.Scan()
 	for !eos && code == lexer.TYPE_SHL || code == lexer.TYPE_SHR {
 		b := p.addedFactor()
 		a = &ast.BinNode{
-			Op:    code,
-			Left:  a,
-			Right: b,
+			Op:      code,
+			Left:    a,
+			Right:   b,
+			Pos:     pos,
+			Lexer:   p.lexer,
+			SrcFile: p.path,
 		}
 		ch = p.lexer.SetCheckpoint()
 		code, _, eos = p.lexer.Scan()
@@ -97,15 +105,19 @@ func (p *Parser) exp() ast.ExpNode {
 	return a
 }
 func (p *Parser) addedFactor() ast.ExpNode {
+	pos := p.lexer.GetPos()
 	a := p.factor()
 	ch := p.lexer.SetCheckpoint()
 	code, _, eos := p.lexer.Scan()
 	for !eos && code == lexer.TYPE_PLUS || code == lexer.TYPE_SUB {
 		b := p.factor()
 		a = &ast.BinNode{
-			Op:    code,
-			Left:  a,
-			Right: b,
+			Op:      code,
+			Left:    a,
+			Right:   b,
+			Pos:     pos,
+			Lexer:   p.lexer,
+			SrcFile: p.path,
 		}
 		ch = p.lexer.SetCheckpoint()
 		code, _, eos = p.lexer.Scan()
@@ -117,13 +129,14 @@ func (p *Parser) addedFactor() ast.ExpNode {
 }
 
 func (p *Parser) symbol() ast.ExpNode {
+	pos := p.lexer.GetPos()
 	ch := p.lexer.SetCheckpoint()
 	code, _, eos := p.lexer.Scan()
 	if eos {
 		panic(lexer.ErrEOS)
 	}
 	if code == lexer.TYPE_PLUS || code == lexer.TYPE_SUB {
-		return &ast.UnaryNode{Op: code, Child: p.number()}
+		return &ast.UnaryNode{Op: code, Child: p.number(), Pos: pos, Lexer: p.lexer, SrcFile: p.path}
 	}
 	p.lexer.GobackTo(ch)
 	return p.number()
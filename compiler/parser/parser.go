@@ -39,14 +39,12 @@ func ParseInt(s string) (int64, *types.IntType, error) {
 	}
 	bw := 8
 	for {
-		var re int64
-		var err error
-		if base != 10 {
+		re, err := strconv.ParseInt(s, base, bw)
+		if err != nil && base != 10 && bw == 64 {
+			// 0xffffffffffffffff etc. is kept as the bit pattern
 			var re1 uint64
 			re1, err = strconv.ParseUint(s, base, bw)
 			re = int64(re1)
-		} else {
-			re, err = strconv.ParseInt(s, base, bw)
 		}
 		if err == nil {
 			return re, types.NewInt(uint64(bw)), err
@@ -176,10 +174,26 @@ func (p *Parser) statement() (n ast.Node) {
 	if err == nil {
 		return astn
 	}
+	astn, err = p.runWithCatch2(p.rangeLoop)
+	if err == nil {
+		return astn
+	}
 	astn, err = p.runWithCatch2(p.forloop)
 	if err == nil {
 		return astn
 	}
+	astn, err = p.runWithCatch2(p.switchST)
+	if err == nil {
+		return astn
+	}
+	astn, err = p.runWithCatch2(p.constDef)
+	if err == nil {
+		return astn
+	}
+	astn, err = p.runWithCatch2(p.commaOk)
+	if err == nil {
+		return astn
+	}
 	astn, err = p.runWithCatch2(p.defineAndAssign)
 	if err == nil {
 		return astn
@@ -224,7 +238,7 @@ func (p *Parser) statement() (n ast.Node) {
 func (p *Parser) statementList() ast.Node {
 	n := &ast.SLNode{}
 	for {
-		n.Children = append(n.Children, p.statement())
+		addStatement(n, p.statement())
 		ch := p.lexer.SetCheckpoint()
 		c, _, _ := p.lexer.Scan()
 		p.lexer.GobackTo(ch)
@@ -282,6 +296,11 @@ func (p *Parser) program() *ast.ProgramNode {
 			n.Children = append(n.Children, ast)
 			continue
 		}
+		ast, err = p.runWithCatch2(p.constDef)
+		if err == nil {
+			n.Children = append(n.Children, ast)
+			continue
+		}
 		ast, err = p.runWithCatch2(p.define)
 		if err == nil {
 			n.Children = append(n.Children, ast)
@@ -414,6 +433,85 @@ func (p *Parser) ifstatement() (n ast.Node, err error) {
 
 }
 
+func (p *Parser) switchST() (n ast.Node, err error) {
+	pos := p.lexer.GetPos()
+	_, err = p.lexer.ScanType(lexer.TYPE_RES_SWITCH)
+	if err != nil {
+		return nil, err
+	}
+	line, _ := p.lexer.Currpos(pos)
+	sn := &ast.SwitchNode{Tag: p.allexp(), File: p.path, Line: line}
+	_, err = p.lexer.ScanType(lexer.TYPE_LB)
+	if err != nil {
+		return nil, err
+	}
+	for {
+		code, _, eos := p.lexer.Scan()
+		if eos {
+			return nil, lexer.ErrEOS
+		}
+		switch code {
+		case lexer.TYPE_RB:
+			return sn, nil
+		case lexer.TYPE_NL:
+		case lexer.TYPE_RES_CASE:
+			line, _ := p.lexer.Currpos(p.lexer.GetPos())
+			cn := &ast.CaseNode{Vals: []ast.ExpNode{p.allexp()}, Line: line}
+			for {
+				_, err = p.lexer.ScanType(lexer.TYPE_COMMA)
+				if err != nil {
+					break
+				}
+				cn.Vals = append(cn.Vals, p.allexp())
+			}
+			_, err = p.lexer.ScanType(lexer.TYPE_COLON)
+			if err != nil {
+				return nil, err
+			}
+			cn.Statements = p.caseBody()
+			sn.Cases = append(sn.Cases, cn)
+		case lexer.TYPE_RES_DEFAULT:
+			if sn.Default != nil {
+				return nil, fmt.Errorf("multiple defaults in switch")
+			}
+			_, err = p.lexer.ScanType(lexer.TYPE_COLON)
+			if err != nil {
+				return nil, err
+			}
+			sn.Default = p.caseBody()
+		default:
+			return nil, fmt.Errorf("expect case or default in switch")
+		}
+	}
+}
+
+// caseBody parses statements until the next case, default or the end of
+// switch
+func (p *Parser) caseBody() ast.Node {
+	n := &ast.SLNode{}
+	for {
+		code, _, eos := p.lexer.PeekToken()
+		if eos {
+			panic(lexer.ErrEOS)
+		}
+		switch code {
+		case lexer.TYPE_RES_CASE, lexer.TYPE_RES_DEFAULT, lexer.TYPE_RB:
+			return n
+		}
+		addStatement(n, p.statement())
+	}
+}
+
+// addStatement appends st to n, statements lowered to several ones are
+// flattened
+func addStatement(n *ast.SLNode, st ast.Node) {
+	if sl, ok := st.(*ast.SLNode); ok {
+		n.Children = append(n.Children, sl.Children...)
+		return
+	}
+	n.Children = append(n.Children, st)
+}
+
 func (p *Parser) defineAndAssign() (n ast.Node, err error) {
 	ch := p.lexer.SetCheckpoint()
 	defer func() {
@@ -616,6 +714,10 @@ func (p *Parser) takeValExp() (n ast.ExpNode, err error) {
 			n = node
 		}
 	}()
+	node, err = p.runWithCatch2Exp(p.mapInit)
+	if err == nil {
+		return &ast.TakeValNode{Node: node, Level: level}, nil
+	}
 	node, err = p.runWithCatch2Exp(p.arrayInit)
 	if err == nil {
 		return &ast.TakeValNode{Node: node, Level: level}, nil
@@ -625,6 +727,10 @@ func (p *Parser) takeValExp() (n ast.ExpNode, err error) {
 		return &ast.TakeValNode{Node: node, Level: level}, nil
 	}
 
+	node, err = p.runWithCatch2Exp(p.convExp)
+	if err == nil {
+		return &ast.TakeValNode{Node: node, Level: level}, nil
+	}
 	node, err = p.runWithCatchExp(p.callFunc)
 	if err == nil {
 		return &ast.TakeValNode{Node: node, Level: level}, nil
@@ -637,6 +743,39 @@ func (p *Parser) takeValExp() (n ast.ExpNode, err error) {
 
 }
 
+// convExp parses conversions like int32(x), []byte(s) and (*T)(p).
+// Conversions to named types look like function calls, they are parsed
+// as CallFuncNode.
+func (p *Parser) convExp() (n ast.ExpNode, err error) {
+	var tp ast.TypeNode
+	_, err = p.lexer.ScanType(lexer.TYPE_LP)
+	paren := err == nil
+	tp, err = p.allTypes()
+	if err != nil {
+		return nil, err
+	}
+	if b, ok := tp.(*ast.BasicTypeNode); ok && len(b.CustomTp) > 0 &&
+		(!paren || b.PtrLevel == 0) {
+		return nil, fmt.Errorf("not a conversion")
+	}
+	if paren {
+		_, err = p.lexer.ScanType(lexer.TYPE_RP)
+		if err != nil {
+			return nil, err
+		}
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_LP)
+	if err != nil {
+		return nil, err
+	}
+	val := p.allexp()
+	_, err = p.lexer.ScanType(lexer.TYPE_RP)
+	if err != nil {
+		return nil, err
+	}
+	return &ast.ConvertNode{TP: tp, Val: val}, nil
+}
+
 func (p *Parser) varChain() (n ast.ExpNode, err error) {
 	head, err := p.varBlock()
 	if err != nil {
@@ -677,7 +816,20 @@ func (p *Parser) varBlock() (n *ast.VarBlockNode, err error) {
 		if err != nil {
 			break
 		}
-		n.Idxs = append(n.Idxs, p.allexp())
+		var idx ast.Node
+		if code, _, _ := p.lexer.PeekToken(); code != lexer.TYPE_COLON {
+			idx = p.allexp()
+		}
+		_, err = p.lexer.ScanType(lexer.TYPE_COLON)
+		if err == nil {
+			// slice expression x[low:high]
+			se := &ast.SliceExpNode{Low: idx}
+			if code, _, _ := p.lexer.PeekToken(); code != lexer.TYPE_RSB {
+				se.High = p.allexp()
+			}
+			idx = se
+		}
+		n.Idxs = append(n.Idxs, idx)
 		_, err = p.lexer.ScanType(lexer.TYPE_RSB)
 		if err != nil {
 			return nil, err
@@ -748,6 +900,7 @@ func ParseDir(dir string) *ir.Module {
 	ParseModule("", "github.com/Chronostasys/calc/runtime", m, map[string]bool{})
 	ParseModule("", "github.com/Chronostasys/calc/runtime/slice", m, map[string]bool{})
 	ParseModule("", "github.com/Chronostasys/calc/runtime/strings", m, map[string]bool{})
+	ParseModule("", "github.com/Chronostasys/calc/runtime/maps", m, map[string]bool{})
 	ParseModule("", "github.com/Chronostasys/calc/runtime/coro", m, map[string]bool{})
 	p1 := ParseModule(dir, "main", m, map[string]bool{})
 	ast.AddSTDFunc(m, p1.GlobalScope)
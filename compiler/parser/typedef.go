@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"fmt"
+
 	"github.com/Chronostasys/calc/compiler/ast"
 	"github.com/Chronostasys/calc/compiler/lexer"
 )
@@ -38,6 +40,10 @@ func (p *Parser) structType() (n ast.TypeNode, err error) {
 		if err == nil {
 			break
 		}
+		if f, err := p.embeddedField(); err == nil {
+			ofs = append(ofs, f)
+			continue
+		}
 		t, err := p.lexer.ScanType(lexer.TYPE_VAR)
 		if err != nil {
 			p.empty()
@@ -52,6 +58,59 @@ func (p *Parser) structType() (n ast.TypeNode, err error) {
 	return &ast.StructDefNode{Orderedfields: ofs}, nil
 }
 
+// embeddedField 匿名字段，比如`sync.Mutex`或`*Buf`，字段名是类型名
+func (p *Parser) embeddedField() (n *ast.Field, err error) {
+	ch := p.lexer.SetCheckpoint()
+	defer func() {
+		if err != nil {
+			p.lexer.GobackTo(ch)
+		}
+	}()
+	tp, err := p.allTypes()
+	if err != nil {
+		return nil, err
+	}
+	b, ok := tp.(*ast.BasicTypeNode)
+	if !ok || len(b.CustomTp) == 0 {
+		return nil, fmt.Errorf("not an embedded field")
+	}
+	code, _, _ := p.lexer.PeekToken()
+	if code != lexer.TYPE_NL && code != lexer.TYPE_RB {
+		return nil, fmt.Errorf("not an embedded field")
+	}
+	return &ast.Field{Name: b.CustomTp[len(b.CustomTp)-1], TP: tp, Embedded: true}, nil
+}
+
+func (p *Parser) enumType() (n ast.TypeNode, err error) {
+	_, err = p.lexer.ScanType(lexer.TYPE_RES_ENUM)
+	if err != nil {
+		return nil, err
+	}
+	_, err = p.lexer.ScanType(lexer.TYPE_LB)
+	if err != nil {
+		return nil, err
+	}
+	members := []string{}
+	for {
+		_, err = p.lexer.ScanType(lexer.TYPE_RB)
+		if err == nil {
+			break
+		}
+		code, t, eos := p.lexer.Scan()
+		if eos {
+			return nil, lexer.ErrEOS
+		}
+		switch code {
+		case lexer.TYPE_NL, lexer.TYPE_COMMA:
+		case lexer.TYPE_VAR:
+			members = append(members, t)
+		default:
+			return nil, fmt.Errorf("unexpected %s in enum", t)
+		}
+	}
+	return &ast.EnumDefNode{Members: members}, nil
+}
+
 func (p *Parser) interfaceType() (n ast.TypeNode, err error) {
 
 	fields := make(map[string]*ast.FuncNode)
@@ -64,11 +123,16 @@ func (p *Parser) interfaceType() (n ast.TypeNode, err error) {
 		return nil, err
 	}
 	names := []string{}
+	embeds := []ast.TypeNode{}
 	for {
 		_, err = p.lexer.ScanType(lexer.TYPE_RB)
 		if err == nil {
 			break
 		}
+		if f, err := p.embeddedField(); err == nil { // 嵌入的接口
+			embeds = append(embeds, f.TP)
+			continue
+		}
 		t, err := p.lexer.ScanType(lexer.TYPE_VAR)
 		if err != nil {
 			p.empty()
@@ -84,5 +148,5 @@ func (p *Parser) interfaceType() (n ast.TypeNode, err error) {
 		names = append(names, t)
 		p.empty()
 	}
-	return &ast.InterfaceDefNode{Funcs: fields, OrderedIDS: names}, nil
+	return &ast.InterfaceDefNode{Funcs: fields, OrderedIDS: names, Embeds: embeds}, nil
 }
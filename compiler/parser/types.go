@@ -25,10 +25,18 @@ func (p *Parser) allTypes() (n ast.TypeNode, err error) {
 	if err == nil {
 		goto END
 	}
+	n, err = p.enumType()
+	if err == nil {
+		goto END
+	}
 	n, err = p.basicTypes()
 	if err == nil {
 		goto END
 	}
+	n, err = p.mapType()
+	if err == nil {
+		goto END
+	}
 	n, err = p.arrayTypes()
 	if err == nil {
 		goto END
@@ -54,11 +62,22 @@ func (p *Parser) arrayTypes() (n ast.TypeNode, err error) {
 	if err != nil {
 		return nil, err
 	}
+	ch1 := p.lexer.SetCheckpoint()
 	t, err := p.lexer.ScanType(lexer.TYPE_INT)
 	if err == nil {
 		arr.Len, _ = strconv.Atoi(t)
-	} else {
+		if code, _, _ := p.lexer.PeekToken(); code != lexer.TYPE_RSB {
+			// not a plain number, like [N*2]int
+			p.lexer.GobackTo(ch1)
+			err = fmt.Errorf("not int")
+		}
+	}
+	if err != nil {
 		arr.Len = -1
+		l, err := p.runWithCatchExp(p.exp)
+		if err == nil {
+			arr.LenExp = l
+		}
 	}
 	_, err = p.lexer.ScanType(lexer.TYPE_RSB)
 	if err != nil {
@@ -1,4 +1,5 @@
 #include "uvutil.h"
+#include <string.h>
 
 uv_timer_t *new_timer2()
 {
@@ -12,9 +13,10 @@ uv_tcp_t *new_tcp()
     return GC_MALLOC(sizeof server);
 }
 
-struct sockaddr_in *new_addr()
+// 使用sockaddr_storage，ipv4和ipv6地址都能放下
+struct sockaddr_storage *new_addr()
 {
-    struct sockaddr_in server;
+    struct sockaddr_storage server;
     return GC_MALLOC(sizeof server);
 }
 
@@ -124,3 +126,295 @@ int get_available_parallelism()
     uv_free_cpu_info(cpu_infos, count);
     return count;
 }
+
+uv_tty_t *new_tty()
+{
+    uv_tty_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+uv_pipe_t *new_pipe()
+{
+    uv_pipe_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+// 0: 未知，1: 终端，2: 管道，3: 文件
+int get_handle_kind(int fd)
+{
+    switch (uv_guess_handle(fd))
+    {
+    case UV_TTY:
+        return 1;
+    case UV_NAMED_PIPE:
+        return 2;
+    case UV_FILE:
+        return 3;
+    default:
+        return 0;
+    }
+}
+
+// 用一个临时的loop打开终端，因为默认loop不是线程安全的
+int get_tty_winsize(int fd, int *width, int *height)
+{
+    uv_loop_t loop;
+    uv_tty_t tty;
+    int err = uv_loop_init(&loop);
+    if (err)
+        return err;
+    err = uv_tty_init(&loop, &tty, fd, 0);
+    if (!err)
+    {
+        err = uv_tty_get_winsize(&tty, width, height);
+        uv_close((uv_handle_t *)&tty, NULL);
+        uv_run(&loop, UV_RUN_DEFAULT);
+    }
+    uv_loop_close(&loop);
+    return err;
+}
+
+// 同步获取文件信息，返回libuv的错误码
+int fs_stat(const char *path, int64_t *size, int *mode, int64_t *mtime)
+{
+    uv_fs_t req;
+    int err = uv_fs_stat(uv_default_loop(), &req, path, NULL);
+    if (!err)
+        fs_req_stat(&req, size, mode, mtime);
+    uv_fs_req_cleanup(&req);
+    return err;
+}
+
+// 同步读取目录，失败时返回NULL，并把错误码写入err
+uv_fs_t *fs_scandir(const char *path, int *err)
+{
+    uv_fs_t *req = GC_MALLOC(sizeof(uv_fs_t));
+    int re = uv_fs_scandir(uv_default_loop(), req, path, 0, NULL);
+    if (re < 0)
+    {
+        *err = re;
+        uv_fs_req_cleanup(req);
+        return NULL;
+    }
+    *err = 0;
+    return req;
+}
+
+// 返回下一个目录项的名字，没有更多目录项时返回NULL。名字在fs_scandir_end之前有效
+const char *fs_scandir_next(uv_fs_t *req, int *type)
+{
+    uv_dirent_t ent;
+    if (uv_fs_scandir_next(req, &ent))
+        return NULL;
+    *type = ent.type;
+    return ent.name;
+}
+
+void fs_scandir_end(uv_fs_t *req)
+{
+    uv_fs_req_cleanup(req);
+}
+
+// 同步创建临时目录，tpl以XXXXXX结尾，成功时把目录名写入path，path的长度和tpl相同
+int fs_mkdtemp(const char *tpl, char *path)
+{
+    uv_fs_t req;
+    int err = uv_fs_mkdtemp(uv_default_loop(), &req, tpl, NULL);
+    if (err >= 0)
+    {
+        err = 0;
+        strcpy(path, req.path);
+    }
+    uv_fs_req_cleanup(&req);
+    return err;
+}
+
+uv_fs_t *new_fs()
+{
+    uv_fs_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+// flags是fs包中的FlagRead等常量的组合，在这里转换成各平台的UV_FS_O_*
+int fs_open(uv_fs_t *req, const char *path, int flags, int mode, uv_fs_cb cb)
+{
+    int f = UV_FS_O_RDONLY;
+    if ((flags & 3) == 3)
+        f = UV_FS_O_RDWR;
+    else if (flags & 2)
+        f = UV_FS_O_WRONLY;
+    if (flags & 4)
+        f |= UV_FS_O_CREAT;
+    if (flags & 8)
+        f |= UV_FS_O_TRUNC;
+    if (flags & 16)
+        f |= UV_FS_O_APPEND;
+    return uv_fs_open(uv_default_loop(), req, path, f, mode, cb);
+}
+
+// uv_fs_read会拷贝bufs，所以buf可以分配在栈上
+int fs_read(uv_fs_t *req, int fd, char *data, ULONG len, int64_t offset, uv_fs_cb cb)
+{
+    uv_buf_t buf = uv_buf_init(data, len);
+    return uv_fs_read(uv_default_loop(), req, fd, &buf, 1, offset, cb);
+}
+
+int fs_write(uv_fs_t *req, int fd, char *data, ULONG len, int64_t offset, uv_fs_cb cb)
+{
+    uv_buf_t buf = uv_buf_init(data, len);
+    return uv_fs_write(uv_default_loop(), req, fd, &buf, 1, offset, cb);
+}
+
+// 从完成的stat请求中读取文件信息
+void fs_req_stat(uv_fs_t *req, int64_t *size, int *mode, int64_t *mtime)
+{
+    uv_stat_t *st = uv_fs_get_statbuf(req);
+    *size = st->st_size;
+    *mode = st->st_mode;
+    *mtime = st->st_mtim.tv_sec;
+}
+
+uv_udp_t *new_udp()
+{
+    uv_udp_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+uv_udp_send_t *new_udp_send()
+{
+    uv_udp_send_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+// calc的字符串不是以0结尾的，先拷贝到dst中
+static int copy_cstr(char *dst, int size, const char *s, int len)
+{
+    if (len < 0 || len >= size)
+        return UV_EINVAL;
+    memcpy(dst, s, len);
+    dst[len] = 0;
+    return 0;
+}
+
+// 把ipv4或者ipv6地址的文本解析到addr中，ip不是合法的地址时返回UV_EINVAL
+int parse_addr(const char *ip, int iplen, int port, struct sockaddr_storage *addr)
+{
+    char buf[64];
+    int re = copy_cstr(buf, sizeof buf, ip, iplen);
+    if (re)
+        return re;
+    if (uv_ip4_addr(buf, port, (struct sockaddr_in *)addr) == 0)
+        return 0;
+    return uv_ip6_addr(buf, port, (struct sockaddr_in6 *)addr);
+}
+
+int udp_bind(uv_udp_t *handle, const char *ip, int iplen, int port, unsigned flags)
+{
+    struct sockaddr_storage addr;
+    int re = parse_addr(ip, iplen, port, &addr);
+    if (re)
+        return re;
+    return uv_udp_bind(handle, (const struct sockaddr *)&addr, flags);
+}
+
+// uv_udp_send会拷贝bufs和addr，所以它们可以分配在栈上
+int udp_send(uv_udp_send_t *req, uv_udp_t *handle, char *data, ULONG len, const char *ip, int iplen, int port, uv_udp_send_cb cb)
+{
+    struct sockaddr_storage addr;
+    int re = parse_addr(ip, iplen, port, &addr);
+    if (re)
+        return re;
+    uv_buf_t buf = uv_buf_init(data, len);
+    return uv_udp_send(req, handle, &buf, 1, (const struct sockaddr *)&addr, cb);
+}
+
+// iface为空时由系统选择网卡
+int udp_set_membership(uv_udp_t *handle, const char *group, int glen, const char *iface, int ilen, int join)
+{
+    char g[64];
+    char i[64];
+    int re = copy_cstr(g, sizeof g, group, glen);
+    if (re)
+        return re;
+    re = copy_cstr(i, sizeof i, iface, ilen);
+    if (re)
+        return re;
+    return uv_udp_set_membership(handle, g, ilen ? i : NULL, join ? UV_JOIN_GROUP : UV_LEAVE_GROUP);
+}
+
+// 把addr中的ip写到dst，返回ip的长度，端口写到port。addr不是ipv4或ipv6地址时返回负数
+int addr_name(const struct sockaddr *addr, char *dst, int size, int *port)
+{
+    int re;
+    if (addr == NULL)
+        return UV_EINVAL;
+    if (addr->sa_family == AF_INET)
+    {
+        const struct sockaddr_in *a = (const struct sockaddr_in *)addr;
+        re = uv_ip4_name(a, dst, size);
+        *port = ntohs(a->sin_port);
+    }
+    else if (addr->sa_family == AF_INET6)
+    {
+        const struct sockaddr_in6 *a = (const struct sockaddr_in6 *)addr;
+        re = uv_ip6_name(a, dst, size);
+        *port = ntohs(a->sin6_port);
+    }
+    else
+        return UV_EAFNOSUPPORT;
+    if (re)
+        return re;
+    return strlen(dst);
+}
+
+uv_getaddrinfo_t *new_getaddrinfo()
+{
+    uv_getaddrinfo_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+// 解析host的ipv4和ipv6地址，每个地址只返回一次。uv_getaddrinfo会拷贝host和hints
+int resolve(uv_getaddrinfo_t *req, const char *host, int hostlen, uv_getaddrinfo_cb cb)
+{
+    char buf[256];
+    struct addrinfo hints;
+    int re = copy_cstr(buf, sizeof buf, host, hostlen);
+    if (re)
+        return re;
+    memset(&hints, 0, sizeof hints);
+    hints.ai_family = AF_UNSPEC;
+    hints.ai_socktype = SOCK_STREAM;
+    return uv_getaddrinfo(uv_default_loop(), req, cb, buf, NULL, &hints);
+}
+
+struct addrinfo *addrinfo_next(struct addrinfo *ai)
+{
+    return ai->ai_next;
+}
+
+struct sockaddr *addrinfo_addr(struct addrinfo *ai)
+{
+    return ai->ai_addr;
+}
+
+uv_shutdown_t *new_shutdown()
+{
+    uv_shutdown_t t;
+    return GC_MALLOC(sizeof t);
+}
+
+// 错误码在各个平台上的值不同，由c这边提供
+int err_canceled()
+{
+    return UV_ECANCELED;
+}
+
+int err_busy()
+{
+    return UV_EBUSY;
+}
+
+int err_noname()
+{
+    return UV_EAI_NONAME;
+}
@@ -7,7 +7,7 @@
 
 uv_timer_t* new_timer2();
 uv_tcp_t* new_tcp();
-struct sockaddr_in* new_addr();
+struct sockaddr_storage* new_addr();
 uv_idle_t* new_idle();
 uv_write_t* new_write();
 uv_async_t* new_async();
@@ -26,3 +26,32 @@ ULONG get_buf_len(uv_buf_t* t);
 void set_buf_data(uv_buf_t* t, char* data);
 void set_buf_len(uv_buf_t* t, ULONG len);
 int get_available_parallelism();
+uv_tty_t* new_tty();
+uv_pipe_t* new_pipe();
+int get_handle_kind(int fd);
+int get_tty_winsize(int fd, int* width, int* height);
+int fs_stat(const char* path, int64_t* size, int* mode, int64_t* mtime);
+uv_fs_t* fs_scandir(const char* path, int* err);
+const char* fs_scandir_next(uv_fs_t* req, int* type);
+void fs_scandir_end(uv_fs_t* req);
+int fs_mkdtemp(const char* tpl, char* path);
+uv_fs_t* new_fs();
+int fs_open(uv_fs_t* req, const char* path, int flags, int mode, uv_fs_cb cb);
+int fs_read(uv_fs_t* req, int fd, char* data, ULONG len, int64_t offset, uv_fs_cb cb);
+int fs_write(uv_fs_t* req, int fd, char* data, ULONG len, int64_t offset, uv_fs_cb cb);
+void fs_req_stat(uv_fs_t* req, int64_t* size, int* mode, int64_t* mtime);
+uv_udp_t* new_udp();
+uv_udp_send_t* new_udp_send();
+int udp_bind(uv_udp_t* handle, const char* ip, int iplen, int port, unsigned flags);
+int udp_send(uv_udp_send_t* req, uv_udp_t* handle, char* data, ULONG len, const char* ip, int iplen, int port, uv_udp_send_cb cb);
+int udp_set_membership(uv_udp_t* handle, const char* group, int glen, const char* iface, int ilen, int join);
+int addr_name(const struct sockaddr* addr, char* dst, int size, int* port);
+int parse_addr(const char* ip, int iplen, int port, struct sockaddr_storage* addr);
+uv_getaddrinfo_t* new_getaddrinfo();
+int resolve(uv_getaddrinfo_t* req, const char* host, int hostlen, uv_getaddrinfo_cb cb);
+struct addrinfo* addrinfo_next(struct addrinfo* ai);
+struct sockaddr* addrinfo_addr(struct addrinfo* ai);
+uv_shutdown_t* new_shutdown();
+int err_canceled();
+int err_busy();
+int err_noname();
@@ -0,0 +1,21 @@
+#!/bin/bash
+# reads the same input through ReadLine and ReadLineAsync, with stdin as
+# a pipe and as a file
+cd "$(dirname "$0")"
+calcc -d . -o console.out >/dev/null || exit 1
+input=$(mktemp)
+printf 'a\r\nb\n\nc' > "$input"
+want=$(printf '<a>\n<b>\n<>\n<c>\neof')
+fail=0
+check() {
+    if [ "$2" != "$want" ]; then
+        echo "case $1 failed: $2"
+        fail=1
+    fi
+}
+check "sync pipe" "$(cat "$input" | ./console.out sync)"
+check "sync file" "$(./console.out sync < "$input")"
+check "async pipe" "$(cat "$input" | ./console.out async)"
+check "async file" "$(./console.out async < "$input")"
+rm -f console.out "$input"
+exit $fail
@@ -0,0 +1,19 @@
+#!/bin/bash
+# runs every case of main.calc, each must exit with code 2 and print the
+# panic message of the nil pointer
+cd "$(dirname "$0")"
+calcc -d . -o nilpanic.out >/dev/null || exit 1
+fail=0
+check() {
+    out=$(./nilpanic.out "$1" 2>&1)
+    code=$?
+    if [ $code -ne 2 ] || ! echo "$out" | grep -qF "$2"; then
+        echo "case $1 failed with exit code $code: $out"
+        fail=1
+    fi
+}
+check load "nil pointer dereference (n.next at"
+check store "nil pointer dereference (n.next at"
+check iface "nil pointer dereference (call of Get on nil interface at"
+rm -f nilpanic.out
+exit $fail
@@ -0,0 +1,19 @@
+#!/bin/bash
+# runs every case of main.calc compiled with -overflow=trap, each must exit
+# with code 2 and print the overflow panic
+cd "$(dirname "$0")"
+calcc -overflow=trap -d . -o overflow.out >/dev/null || exit 1
+fail=0
+check() {
+    out=$(./overflow.out "$1" 2>&1)
+    code=$?
+    if [ $code -ne 2 ] || ! echo "$out" | grep -qF "integer overflow (" ; then
+        echo "case $1 failed with exit code $code: $out"
+        fail=1
+    fi
+}
+check add
+check mul
+check neg
+rm -f overflow.out
+exit $fail